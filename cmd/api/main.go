@@ -8,12 +8,14 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
 
 	_ "auto-message-sender/docs"
+	"auto-message-sender/internal/channel"
 	"auto-message-sender/internal/client"
 	"auto-message-sender/internal/config"
 	"auto-message-sender/internal/database"
@@ -49,11 +51,24 @@ func main() {
 	}
 
 	messageRepo := repository.NewMessageRepository(db)
-	webhookClient := client.NewWebhookClient()
 	redisSvc := service.NewRedisService()
-	messageSvc := service.NewMessageService(messageRepo, webhookClient, redisSvc)
+	rateLimiter := service.NewRateLimiter(redisSvc)
+	webhookClient, err := client.NewWebhookClient(rateLimiter)
+	if err != nil {
+		logger.Fatalf("Failed to setup webhook client: %v", err)
+	}
+	channels, err := newChannelRegistry(webhookClient)
+	if err != nil {
+		logger.Fatalf("Failed to setup delivery channels: %v", err)
+	}
+	messageSvc := service.NewMessageService(messageRepo, channels, redisSvc)
+
+	importSvc := service.NewImportService(messageRepo, redisSvc)
 
 	messageHandler := handler.NewMessageHandler(messageSvc)
+	importHandler := handler.NewImportHandler(importSvc)
+	callbackHandler := handler.NewCallbackHandler(messageSvc)
+	rateLimitHandler := handler.NewRateLimitHandler(rateLimiter)
 
 	e := echo.New()
 
@@ -64,16 +79,25 @@ func main() {
 	e.Use(middleware.CORS())
 
 	routerConfig := router.Config{
-		MessageHandler: messageHandler,
+		MessageHandler:   messageHandler,
+		ImportHandler:    importHandler,
+		CallbackHandler:  callbackHandler,
+		RateLimitHandler: rateLimitHandler,
 		HealthConfig: health.Config{
 			Version: appVersion,
 			DB:      db,
+			Indicators: map[string]health.Indicator{
+				"webhook": webhookHealth(webhookClient),
+			},
 		},
 	}
 	router.SetupRoutes(e, routerConfig)
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	appContext, appCancel = context.WithCancel(context.Background())
+	if err := messageSvc.RecoverInterruptedSends(appContext); err != nil {
+		logger.Errorf("Failed to recover interrupted sends: %v", err)
+	}
 	if err := messageSvc.StartSending(appContext); err != nil {
 		logger.Errorf("Failed to start message sending: %v", err)
 	} else {
@@ -101,3 +125,73 @@ func main() {
 		log.Fatal("Error starting server:", err)
 	}
 }
+
+// newChannelRegistry registers a sender for every enabled delivery channel.
+func newChannelRegistry(webhookClient client.WebhookClient) (*channel.Registry, error) {
+	var senders []channel.Sender
+	if config.AppSettings.Webhook.Enabled {
+		senders = append(senders, channel.NewWebhookSender(webhookClient))
+	}
+	if config.AppSettings.File.Enabled {
+		fileSender, err := channel.NewFileSender(config.AppSettings.File.Path)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, fileSender)
+	}
+	if config.AppSettings.Email.Enabled {
+		smtpClient, err := client.NewSMTPClient()
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, channel.NewEmailSender(smtpClient))
+	}
+
+	registry := channel.NewRegistry(senders...)
+	logger.WithField("channels", registry.Names()).Info("Delivery channels enabled")
+	return registry, nil
+}
+
+// webhookHealth reports the webhook providers as seen by their circuit
+// breakers. The webhook is down when every provider's circuit is open and
+// degraded while any of them is not closed.
+func webhookHealth(webhookClient client.WebhookClient) health.Indicator {
+	return func() (health.Status, interface{}) {
+		states := webhookClient.CircuitStates()
+
+		details := make(map[string]interface{}, len(states))
+		open := 0
+		degraded := false
+		for provider, state := range states {
+			providerDetails := map[string]interface{}{
+				"circuit":      state.State,
+				"failure_rate": state.FailureRate,
+				"requests":     state.Requests,
+			}
+			if !state.OpenedAt.IsZero() {
+				providerDetails["opened_at"] = state.OpenedAt.Format(time.RFC3339)
+			}
+			if !state.RetryAt.IsZero() {
+				providerDetails["retry_at"] = state.RetryAt.Format(time.RFC3339)
+			}
+			details[provider] = providerDetails
+
+			switch state.State {
+			case client.CircuitOpen:
+				open++
+				degraded = true
+			case client.CircuitHalfOpen:
+				degraded = true
+			}
+		}
+
+		switch {
+		case len(states) > 0 && open == len(states):
+			return health.StatusDown, details
+		case degraded:
+			return health.StatusDegraded, details
+		default:
+			return health.StatusUp, details
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/database"
+	"auto-message-sender/internal/importer"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/repository"
+	"auto-message-sender/internal/service"
+	"auto-message-sender/pkg/logger"
+)
+
+func main() {
+	filePath := flag.String("file", "", "CSV or NDJSON file to import, - for stdin")
+	format := flag.String("format", "", "File format (csv/ndjson), detected from the file extension when omitted")
+	toColumn := flag.String("to-column", "to", "Column holding the recipient")
+	contentColumn := flag.String("content-column", "content", "Column holding the message content")
+	subjectColumn := flag.String("subject-column", "", "Column holding the email subject")
+	htmlColumn := flag.String("html-column", "", "Column holding the email HTML body")
+	contentTemplate := flag.String("template", "", "Go text/template rendering the content from the row's columns")
+	priority := flag.String("priority", "", "Priority of the imported messages (high/normal/bulk)")
+	channel := flag.String("channel", "", "Delivery channel of the imported messages, the configured default when omitted")
+	errorReport := flag.String("errors", "", "Write the rejected rows to this CSV file")
+	flag.Parse()
+
+	logger.Init(logger.InfoLevel)
+
+	if *filePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*filePath)) {
+		case ".csv":
+			*format = importer.FormatCSV
+		case ".ndjson", ".jsonl":
+			*format = importer.FormatNDJSON
+		}
+	}
+
+	req := &request.ImportMessagesRequest{
+		Format:        *format,
+		ToColumn:      *toColumn,
+		ContentColumn: *contentColumn,
+		SubjectColumn: *subjectColumn,
+		HTMLColumn:    *htmlColumn,
+		Template:      *contentTemplate,
+		Priority:      *priority,
+		Channel:       *channel,
+	}
+
+	if err := config.LoadSettings(); err != nil {
+		logger.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if err := req.Validate(); err != nil {
+		logger.Fatalf("Invalid import options: %v", err)
+	}
+
+	db, err := database.Setup()
+	if err != nil {
+		logger.Fatalf("Failed to setup database: %v", err)
+	}
+
+	input := os.Stdin
+	if *filePath != "-" {
+		input, err = os.Open(*filePath)
+		if err != nil {
+			logger.Fatalf("Failed to open %s: %v", *filePath, err)
+		}
+		defer input.Close()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	importSvc := service.NewImportService(repository.NewMessageRepository(db), service.NewRedisService())
+
+	summary, importErr := importSvc.Import(ctx, input, service.ImportOptions{
+		Format:          req.Format,
+		ToColumn:        req.ToColumn,
+		ContentColumn:   req.ContentColumn,
+		ContentTemplate: req.Template,
+		SubjectColumn:   req.SubjectColumn,
+		HTMLColumn:      req.HTMLColumn,
+		Priority:        req.Priority,
+		Channel:         req.Channel,
+	})
+	if summary == nil {
+		logger.Fatalf("Import failed: %v", importErr)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(summary); err != nil {
+		logger.Errorf("Failed to print import summary: %v", err)
+	}
+
+	if *errorReport != "" && summary.Rejected+summary.Duplicates > 0 {
+		if err := writeErrorReport(ctx, importSvc, summary.ImportID, *errorReport); err != nil {
+			logger.Errorf("Failed to write error report: %v", err)
+		}
+	}
+
+	if importErr != nil {
+		logger.Fatalf("Import failed: %v", importErr)
+	}
+}
+
+func writeErrorReport(ctx context.Context, importSvc service.ImportService, importID, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := importSvc.WriteErrorReport(ctx, importID, out); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
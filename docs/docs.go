@@ -15,9 +15,169 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/callbacks/delivery": {
+            "post": {
+                "description": "Called by the provider with the handset delivery outcome of a sent message. The request must carry X-Signature-Timestamp (unix seconds) and X-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + \".\" + body)). Providers configured under webhook.providers post to /callbacks/delivery/{provider}; /callbacks/delivery is for the default provider.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "callbacks"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sha256= followed by the hex HMAC-SHA256 of timestamp.body",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time the request was signed at",
+                        "name": "X-Signature-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Delivery receipt",
+                        "name": "receipt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/request.DeliveryReceiptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/callbacks/delivery/{provider}": {
+            "post": {
+                "description": "Called by the provider with the handset delivery outcome of a sent message. The request must carry X-Signature-Timestamp (unix seconds) and X-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + \".\" + body)). Providers configured under webhook.providers post to /callbacks/delivery/{provider}; /callbacks/delivery is for the default provider.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "callbacks"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "sha256= followed by the hex HMAC-SHA256 of timestamp.body",
+                        "name": "X-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time the request was signed at",
+                        "name": "X-Signature-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of the webhook provider sending the receipt",
+                        "name": "provider",
+                        "in": "path"
+                    },
+                    {
+                        "description": "Delivery receipt",
+                        "name": "receipt",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/request.DeliveryReceiptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/messages": {
             "get": {
-                "description": "Get a list of messages with optional filtering",
+                "description": "Get a list of messages with optional filtering. Without a status filter sent, delivered and undelivered messages are returned.",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,23 +189,83 @@ const docTemplate = `{
                 ],
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Message status (pending/sent/failed)",
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "default": "sent",
+                        "description": "Message statuses (pending/sent/failed/cancelled/delivered/undelivered), repeated or comma separated; 'all' for every status",
                         "name": "status",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Start date (YYYY-MM-DD)",
+                        "description": "Recipient phone number or email address (exact match)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient phone number prefix, e.g. +90532",
+                        "name": "to_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive content substring",
+                        "name": "content",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook message ID",
+                        "name": "message_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery channel (webhook/file/email)",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider that accepted the message",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sent at start date (YYYY-MM-DD)",
                         "name": "start_date",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "End date (YYYY-MM-DD)",
+                        "description": "Sent at end date (YYYY-MM-DD)",
                         "name": "end_date",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Created at lower bound (YYYY-MM-DD or RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at upper bound (YYYY-MM-DD or RFC3339, dates are inclusive)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-sent_at",
+                        "description": "Sort field (sent_at/created_at/send_at/to/status/priority), prefix with '-' for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "minimum": 1,
                         "type": "integer",
@@ -62,6 +282,19 @@ const docTemplate = `{
                         "description": "Page size",
                         "name": "page_size",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "offset",
+                        "description": "Pagination mode (offset/cursor)",
+                        "name": "pagination",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque next_cursor from the previous page; implies cursor pagination",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -86,7 +319,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Create a new message to be sent",
+                "description": "Create a new message to be sent, optionally scheduled with an RFC3339 send_at",
                 "consumes": [
                     "application/json"
                 ],
@@ -97,6 +330,12 @@ const docTemplate = `{
                     "messages"
                 ],
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Unique key that makes retries of this request safe",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "Message details",
                         "name": "message",
@@ -120,6 +359,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ValidationErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -129,9 +374,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/start": {
+        "/messages/batch": {
             "post": {
-                "description": "Start the automatic message sending process",
+                "description": "Validate each message of the batch and queue the valid ones; the result of every item is reported by its index",
                 "consumes": [
                     "application/json"
                 ],
@@ -141,11 +386,31 @@ const docTemplate = `{
                 "tags": [
                     "messages"
                 ],
+                "parameters": [
+                    {
+                        "description": "Messages to create",
+                        "name": "messages",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/request.SendMessageRequest"
+                            }
+                        }
+                    }
+                ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/response.SuccessResponse"
+                            "$ref": "#/definitions/response.BatchMessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.BatchMessageResponse"
                         }
                     },
                     "500": {
@@ -157,9 +422,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/stop": {
-            "post": {
-                "description": "Stop the automatic message sending process",
+        "/messages/dispatcher": {
+            "get": {
+                "description": "Get the current settings of the sending loop and the utilisation of its worker pool",
                 "consumes": [
                     "application/json"
                 ],
@@ -173,7 +438,44 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.SuccessResponse"
+                            "$ref": "#/definitions/response.DispatcherResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change the interval, batch size, per-tick limit or worker count of the sending loop without a restart",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "description": "Dispatcher settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/request.UpdateDispatcherRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.DispatcherResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
                         }
                     },
                     "500": {
@@ -184,51 +486,954 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "request.SendMessageRequest": {
-            "type": "object",
-            "required": [
-                "content",
-                "to"
-            ],
-            "properties": {
-                "content": {
-                    "type": "string",
-                    "maxLength": 160
-                },
-                "to": {
-                    "type": "string"
-                }
-            }
-        },
-        "response.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                }
-            }
         },
-        "response.MessageItem": {
-            "type": "object",
-            "properties": {
-                "content": {
-                    "type": "string"
-                },
-                "id": {
+        "/messages/export": {
+            "get": {
+                "description": "Stream every message matching the filters as CSV, NDJSON or columnar NDJSON. The columnar format writes one JSON object per group of up to 1000 messages, holding an array per column. Without a status filter sent, delivered and undelivered messages are exported. A failure after the download has started aborts the connection, so a partial file is never mistaken for a complete one.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "csv",
+                        "description": "Export format (csv/ndjson/columnar)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "default": "sent",
+                        "description": "Message statuses (pending/sent/failed/cancelled/delivered/undelivered), repeated or comma separated; 'all' for every status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient phone number or email address (exact match)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient phone number prefix, e.g. +90532",
+                        "name": "to_prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive content substring",
+                        "name": "content",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook message ID",
+                        "name": "message_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery channel (webhook/file/email)",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Provider that accepted the message",
+                        "name": "provider",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sent at start date (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sent at end date (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at lower bound (YYYY-MM-DD or RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at upper bound (YYYY-MM-DD or RFC3339, dates are inclusive)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "-sent_at",
+                        "description": "Sort field (sent_at/created_at/send_at/to/status/priority), prefix with '-' for descending",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported messages",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/import": {
+            "post": {
+                "description": "Stream a CSV (with a header row) or NDJSON file, either as the raw request body or as the \"file\" field of a multipart form, and queue every valid row as a pending message",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File format (csv/ndjson), detected from the content type or file name when omitted",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "to",
+                        "description": "Column holding the recipient",
+                        "name": "to_column",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "content",
+                        "description": "Column holding the message content",
+                        "name": "content_column",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Column holding the email subject",
+                        "name": "subject_column",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Column holding the email HTML body",
+                        "name": "html_column",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Go text/template rendering the content from the row's columns, e.g. Hello {{.name}}",
+                        "name": "template",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Priority of the imported messages (high/normal/bulk)",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery channel of the imported messages, the configured default when omitted",
+                        "name": "channel",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/response.ImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ImportResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/import/{id}": {
+            "get": {
+                "description": "Get the counts of accepted, rejected and duplicate rows of an import",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Import ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ImportResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/import/{id}/errors": {
+            "get": {
+                "description": "Download the rejected and duplicate rows of an import as CSV with the columns line, reason and row",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Import ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV error report",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/rate-limits": {
+            "get": {
+                "description": "Get the provider and recipient rate limits with the tokens left in their shared buckets. Recipient buckets are per number, so their tokens are only included for the recipient given in to. The allowed, deferred and errors counters are for the instance serving the request.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Recipient whose bucket to include",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.RateLimitResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/start": {
+            "post": {
+                "description": "Start the automatic message sending process",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/stop": {
+            "post": {
+                "description": "Stop the automatic message sending process",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{id}": {
+            "get": {
+                "description": "Get a single message with its full delivery lifecycle",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Cancel a message that has not been claimed for sending yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Change the recipient, content or schedule of a message that has not been claimed for sending yet; an empty send_at sends it on the next dispatch",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/request.UpdateMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/{id}/events": {
+            "get": {
+                "description": "List every state change of a message, oldest first: creation, edits, claims, attempts, retries, failure and delivery",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Message ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.MessageEventListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "request.DeliveryReceiptRequest": {
+            "type": "object",
+            "required": [
+                "messageId",
+                "status"
+            ],
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "messageId": {
+                    "type": "string",
+                    "example": "67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "delivered",
+                        "undelivered"
+                    ],
+                    "example": "delivered"
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2025-01-02T15:04:05Z"
+                }
+            }
+        },
+        "request.SendMessageRequest": {
+            "type": "object",
+            "required": [
+                "to"
+            ],
+            "properties": {
+                "channel": {
+                    "type": "string",
+                    "example": "webhook"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "html": {
+                    "type": "string",
+                    "example": "\u003cp\u003eYour order has shipped\u003c/p\u003e"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "high",
+                        "normal",
+                        "bulk"
+                    ],
+                    "example": "normal"
+                },
+                "send_at": {
+                    "type": "string",
+                    "example": "2025-01-02T15:04:05+03:00"
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "Your order has shipped"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "request.UpdateDispatcherRequest": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "interval": {
+                    "type": "string",
+                    "example": "30s"
+                },
+                "max_per_tick": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "workers": {
+                    "type": "integer",
+                    "example": 8
+                }
+            }
+        },
+        "request.UpdateMessageRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "Updated content"
+                },
+                "html": {
+                    "type": "string",
+                    "example": "\u003cp\u003eUpdated content\u003c/p\u003e"
+                },
+                "send_at": {
+                    "type": "string",
+                    "example": "2025-01-02T15:04:05+03:00"
+                },
+                "subject": {
+                    "type": "string",
+                    "example": "Updated subject"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "+905551111111"
+                }
+            }
+        },
+        "response.BatchMessageResponse": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.BatchMessageResult"
+                    }
+                }
+            }
+        },
+        "response.BatchMessageResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "messageId": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.DispatcherResponse": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "max_per_tick": {
+                    "type": "integer"
+                },
+                "pool": {
+                    "$ref": "#/definitions/response.WorkerPoolResponse"
+                },
+                "running": {
+                    "type": "boolean"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "response.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                },
+                "duplicates": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "error_report_url": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "import_id": {
+                    "type": "string"
+                },
+                "rejected": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "response.MessageDetailResponse": {
+            "type": "object",
+            "properties": {
+                "attempt_count": {
+                    "type": "integer"
+                },
+                "cached_sent_at": {
+                    "type": "string"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "claimed_by": {
+                    "type": "string"
+                },
+                "claimed_until": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivery_error": {
+                    "type": "string"
+                },
+                "delivery_reported_at": {
+                    "type": "string"
+                },
+                "html": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "send_at": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.MessageEventItem": {
+            "type": "object",
+            "properties": {
+                "attempt_count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "retry_scheduled"
+                }
+            }
+        },
+        "response.MessageEventListResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.MessageEventItem"
+                    }
+                },
+                "message_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "response.MessageItem": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "string"
                 },
                 "message_id": {
                     "type": "string"
                 },
+                "priority": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "send_at": {
+                    "type": "string"
+                },
                 "sent_at": {
                     "type": "string"
                 },
                 "status": {
                     "type": "string"
                 },
+                "subject": {
+                    "type": "string"
+                },
                 "to": {
                     "type": "string"
                 }
@@ -243,6 +1448,9 @@ const docTemplate = `{
                         "$ref": "#/definitions/response.MessageItem"
                     }
                 },
+                "next_cursor": {
+                    "type": "string"
+                },
                 "page": {
                     "type": "integer"
                 },
@@ -268,6 +1476,49 @@ const docTemplate = `{
                 }
             }
         },
+        "response.RateLimitBucketResponse": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "integer"
+                },
+                "burst": {
+                    "type": "integer"
+                },
+                "deferred": {
+                    "type": "integer"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "number"
+                }
+            }
+        },
+        "response.RateLimitResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "providers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/response.RateLimitBucketResponse"
+                    }
+                },
+                "recipient": {
+                    "$ref": "#/definitions/response.RateLimitBucketResponse"
+                }
+            }
+        },
         "response.SuccessResponse": {
             "type": "object",
             "properties": {
@@ -289,6 +1540,26 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "response.WorkerPoolResponse": {
+            "type": "object",
+            "properties": {
+                "busy": {
+                    "type": "integer"
+                },
+                "processed": {
+                    "type": "integer"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "utilisation": {
+                    "type": "number"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,30 @@
+package channel
+
+import (
+	"context"
+
+	"auto-message-sender/internal/client"
+	"auto-message-sender/internal/entity"
+)
+
+const emailProvider = "smtp"
+
+type emailSender struct {
+	client client.SMTPClient
+}
+
+func NewEmailSender(smtpClient client.SMTPClient) Sender {
+	return &emailSender{client: smtpClient}
+}
+
+func (s *emailSender) Name() string {
+	return entity.ChannelEmail
+}
+
+func (s *emailSender) Send(ctx context.Context, message entity.Message) (Result, error) {
+	messageID, err := s.client.SendEmail(ctx, message)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Provider: emailProvider, MessageID: messageID}, nil
+}
@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"auto-message-sender/internal/entity"
+)
+
+// fileSender appends every message as a JSON line to a file or to stdout. It
+// is meant for local development, where no real provider is available.
+type fileSender struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+type fileRecord struct {
+	ID        string `json:"id"`
+	MessageID string `json:"message_id"`
+	Channel   string `json:"channel"`
+	To        string `json:"to"`
+	Content   string `json:"content"`
+	Priority  string `json:"priority"`
+	SentAt    string `json:"sent_at"`
+}
+
+// NewFileSender writes to path, or to stdout when path is "stdout" or "-".
+func NewFileSender(path string) (Sender, error) {
+	if path == "" || path == "stdout" || path == "-" {
+		return &fileSender{writer: os.Stdout}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file channel output: %w", err)
+	}
+	return &fileSender{writer: file}, nil
+}
+
+func (s *fileSender) Name() string {
+	return entity.ChannelFile
+}
+
+func (s *fileSender) Send(ctx context.Context, message entity.Message) (Result, error) {
+	messageID := uuid.NewString()
+	line, err := json.Marshal(fileRecord{
+		ID:        message.ID.String(),
+		MessageID: messageID,
+		Channel:   message.Channel,
+		To:        message.To,
+		Content:   message.Content,
+		Priority:  message.Priority,
+		SentAt:    time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return Result{}, fmt.Errorf("failed to write message to file channel: %w", err)
+	}
+	return Result{Provider: entity.ChannelFile, MessageID: messageID}, nil
+}
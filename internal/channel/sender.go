@@ -0,0 +1,100 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"auto-message-sender/internal/entity"
+)
+
+var (
+	// ErrUnknownChannel is returned for a channel that is not registered, for
+	// example because it was disabled after the message was queued.
+	ErrUnknownChannel = errors.New("delivery channel is not enabled")
+	// ErrPaused is wrapped in the DeferredError of a sender that cannot take
+	// messages right now, for example because its circuit breaker is open.
+	ErrPaused = errors.New("delivery channel is paused")
+)
+
+// DeferredError is returned by a sender that must not send the message before
+// RetryAfter has passed, for example because of a rate limit. The message was
+// not sent and should be requeued for then without counting an attempt.
+type DeferredError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *DeferredError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeferredError) Unwrap() error {
+	return e.Err
+}
+
+// Sender delivers a message over one channel. Errors are classified with
+// client.IsRetryable, so senders wrap them in client.DeliveryError where the
+// distinction matters.
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, message entity.Message) (Result, error)
+}
+
+// Result names the provider that accepted a message and the ID it assigned
+// to it.
+type Result struct {
+	Provider  string
+	MessageID string
+}
+
+// Pausable is implemented by senders that can be temporarily unavailable,
+// such as a webhook behind an open circuit breaker. The dispatcher does not
+// claim messages for a paused channel.
+type Pausable interface {
+	Paused() bool
+}
+
+type Registry struct {
+	senders map[string]Sender
+}
+
+func NewRegistry(senders ...Sender) *Registry {
+	registry := &Registry{senders: make(map[string]Sender, len(senders))}
+	for _, sender := range senders {
+		registry.senders[sender.Name()] = sender
+	}
+	return registry
+}
+
+func (r *Registry) Get(name string) (Sender, error) {
+	sender, ok := r.senders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, name)
+	}
+	return sender, nil
+}
+
+// Names returns the registered channels in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.senders))
+	for name := range r.senders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Available returns the registered channels that are not paused.
+func (r *Registry) Available() []string {
+	var available []string
+	for _, name := range r.Names() {
+		if pausable, ok := r.senders[name].(Pausable); ok && pausable.Paused() {
+			continue
+		}
+		available = append(available, name)
+	}
+	return available
+}
@@ -0,0 +1,51 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auto-message-sender/internal/client"
+	"auto-message-sender/internal/entity"
+)
+
+type webhookSender struct {
+	client client.WebhookClient
+}
+
+func NewWebhookSender(webhookClient client.WebhookClient) Sender {
+	return &webhookSender{client: webhookClient}
+}
+
+func (s *webhookSender) Name() string {
+	return entity.ChannelWebhook
+}
+
+func (s *webhookSender) Send(ctx context.Context, message entity.Message) (Result, error) {
+	delivery, err := s.client.SendMessage(ctx, message)
+	var rateLimitErr *client.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return Result{}, &DeferredError{RetryAfter: rateLimitErr.RetryAfter, Err: err}
+	}
+	if errors.Is(err, client.ErrCircuitOpen) {
+		// The message waits for the circuit to close instead of being claimed
+		// again on every tick.
+		var deliveryErr *client.DeliveryError
+		errors.As(err, &deliveryErr)
+		return Result{}, &DeferredError{RetryAfter: deliveryErr.RetryAfter, Err: fmt.Errorf("%w: %w", ErrPaused, err)}
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Provider: delivery.Provider, MessageID: delivery.MessageID}, nil
+}
+
+// Paused reports whether the circuit of every provider is open.
+func (s *webhookSender) Paused() bool {
+	for _, state := range s.client.CircuitStates() {
+		if state.State != client.CircuitOpen {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/pkg/logger"
+)
+
+// tokenRefreshMargin renews OAuth2 tokens this long before they expire so a
+// request never goes out with a token that lapses in flight.
+const tokenRefreshMargin = 30 * time.Second
+
+// Authenticator adds credentials to an outbound webhook request.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// tokenInvalidator is implemented by authenticators holding cached tokens
+// that should be dropped when the webhook rejects them.
+type tokenInvalidator interface {
+	Invalidate()
+}
+
+func NewAuthenticator(auth config.WebhookAuth, httpClient *http.Client) Authenticator {
+	switch auth.Type {
+	case config.WebhookAuthHeader:
+		return &headerAuth{name: auth.HeaderName, value: auth.HeaderValue}
+	case config.WebhookAuthBearer:
+		return &headerAuth{name: "Authorization", value: "Bearer " + auth.Token}
+	case config.WebhookAuthBasic:
+		return &basicAuth{username: auth.Username, password: auth.Password}
+	case config.WebhookAuthOAuth2:
+		return &oauth2Auth{
+			client:       httpClient,
+			tokenURL:     auth.TokenURL,
+			clientID:     auth.ClientID,
+			clientSecret: auth.ClientSecret,
+			scopes:       auth.Scopes,
+		}
+	default:
+		return noAuth{}
+	}
+}
+
+type noAuth struct{}
+
+func (noAuth) Authenticate(*http.Request) error {
+	return nil
+}
+
+type headerAuth struct {
+	name  string
+	value string
+}
+
+func (a *headerAuth) Authenticate(req *http.Request) error {
+	req.Header.Set(a.name, a.value)
+	return nil
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a *basicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// oauth2Auth implements the OAuth2 client credentials grant. The access token
+// is cached and shared by all workers until shortly before it expires.
+type oauth2Auth struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (a *oauth2Auth) Authenticate(req *http.Request) error {
+	token, err := a.accessToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *oauth2Auth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+func (a *oauth2Auth) accessToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expiresAt) {
+		return a.token, nil
+	}
+
+	token, expiresIn, err := a.fetchToken()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"tokenURL": a.tokenURL,
+			"error":    err.Error(),
+		}).Error("Failed to obtain OAuth2 access token")
+		return "", err
+	}
+
+	a.token = token
+	a.expiresAt = time.Now().Add(expiresIn - tokenRefreshMargin)
+	logger.WithFields(logrus.Fields{
+		"tokenURL":  a.tokenURL,
+		"expiresIn": expiresIn.String(),
+	}).Info("Obtained OAuth2 access token")
+	return token, nil
+}
+
+func (a *oauth2Auth) fetchToken() (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+	}
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response has no access_token")
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type: %s", body.TokenType)
+	}
+
+	expiresIn := time.Duration(body.ExpiresIn) * time.Second
+	if expiresIn <= tokenRefreshMargin {
+		// Tokens without a usable lifetime are cached only briefly.
+		expiresIn = tokenRefreshMargin + time.Minute
+	}
+	return body.AccessToken, expiresIn, nil
+}
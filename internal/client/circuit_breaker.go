@@ -0,0 +1,239 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/pkg/logger"
+)
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen is returned instead of calling the provider while the
+// circuit breaker is open or all half-open probes are in flight.
+var ErrCircuitOpen = errors.New("webhook circuit breaker is open")
+
+type CircuitBreakerSettings struct {
+	Enabled        bool
+	WindowSize     int
+	MinRequests    int
+	FailureRate    float64
+	OpenDuration   time.Duration
+	HalfOpenProbes int
+}
+
+type CircuitState struct {
+	State       string
+	FailureRate float64
+	Requests    int
+	OpenedAt    time.Time
+	RetryAt     time.Time
+	Probes      int
+}
+
+// circuitBreaker tracks the outcome of the last WindowSize requests. Once at
+// least MinRequests have been seen and the share of failures reaches
+// FailureRate it opens for OpenDuration, then lets HalfOpenProbes requests
+// through. The circuit closes when every probe succeeds and opens again on
+// the first failed probe.
+type circuitBreaker struct {
+	provider string
+	settings CircuitBreakerSettings
+
+	mu       sync.Mutex
+	state    string
+	outcomes []bool
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	inFlight int
+	passed   int
+	// generation changes on every state transition so outcomes of requests
+	// allowed under an earlier state are not counted against the current one.
+	generation uint64
+}
+
+// circuitTicket is handed out by allow and returned to record.
+type circuitTicket struct {
+	generation uint64
+	probe      bool
+}
+
+func newCircuitBreaker(provider string, settings CircuitBreakerSettings) *circuitBreaker {
+	if settings.WindowSize < 1 {
+		settings.WindowSize = 1
+	}
+	if settings.HalfOpenProbes < 1 {
+		settings.HalfOpenProbes = 1
+	}
+
+	return &circuitBreaker{
+		provider: provider,
+		settings: settings,
+		state:    CircuitClosed,
+		outcomes: make([]bool, settings.WindowSize),
+	}
+}
+
+// allow reports whether a request may be sent. Every allowed request must be
+// followed by exactly one call to record with the returned ticket.
+func (b *circuitBreaker) allow() (circuitTicket, error) {
+	if !b.settings.Enabled {
+		return circuitTicket{}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	ticket := circuitTicket{generation: b.generation}
+	switch b.state {
+	case CircuitOpen:
+		return ticket, ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.inFlight >= b.settings.HalfOpenProbes-b.passed {
+			return ticket, ErrCircuitOpen
+		}
+		b.inFlight++
+		ticket.probe = true
+	}
+	return ticket, nil
+}
+
+// release returns a ticket whose request was never sent, so it counts
+// neither way and a half-open probe slot is freed for another request.
+func (b *circuitBreaker) release(ticket circuitTicket) {
+	if !b.settings.Enabled || !ticket.probe {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation == b.generation {
+		b.inFlight--
+	}
+}
+
+func (b *circuitBreaker) record(ticket circuitTicket, success bool) {
+	if !b.settings.Enabled {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation != b.generation {
+		return
+	}
+
+	if ticket.probe {
+		b.inFlight--
+		if !success {
+			b.open(time.Now())
+			return
+		}
+		b.passed++
+		if b.passed >= b.settings.HalfOpenProbes {
+			b.close()
+		}
+		return
+	}
+
+	if b.count == len(b.outcomes) {
+		if !b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.count++
+	}
+	b.outcomes[b.next] = success
+	b.next = (b.next + 1) % len(b.outcomes)
+	if !success {
+		b.failures++
+	}
+
+	if b.count >= b.settings.MinRequests && b.failureRate() >= b.settings.FailureRate {
+		b.open(time.Now())
+	}
+}
+
+func (b *circuitBreaker) snapshot() CircuitState {
+	if !b.settings.Enabled {
+		return CircuitState{State: CircuitClosed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	state := CircuitState{
+		State:       b.state,
+		FailureRate: b.failureRate(),
+		Requests:    b.count,
+		OpenedAt:    b.openedAt,
+	}
+	switch b.state {
+	case CircuitOpen:
+		state.RetryAt = b.openedAt.Add(b.settings.OpenDuration)
+	case CircuitHalfOpen:
+		state.Probes = b.settings.HalfOpenProbes - b.passed - b.inFlight
+	}
+	return state
+}
+
+func (b *circuitBreaker) failureRate() float64 {
+	if b.count == 0 {
+		return 0
+	}
+	return float64(b.failures) / float64(b.count)
+}
+
+func (b *circuitBreaker) advance(now time.Time) {
+	if b.state == CircuitOpen && !now.Before(b.openedAt.Add(b.settings.OpenDuration)) {
+		b.state = CircuitHalfOpen
+		b.generation++
+		b.inFlight = 0
+		b.passed = 0
+		logger.WithFields(logrus.Fields{
+			"provider": b.provider,
+			"probes":   b.settings.HalfOpenProbes,
+		}).Info("Webhook circuit breaker half-open, probing provider")
+	}
+}
+
+func (b *circuitBreaker) open(now time.Time) {
+	logger.WithFields(logrus.Fields{
+		"provider":      b.provider,
+		"previousState": b.state,
+		"failureRate":   b.failureRate(),
+		"requests":      b.count,
+		"openDuration":  b.settings.OpenDuration.String(),
+	}).Warn("Webhook circuit breaker opened, pausing the provider")
+
+	b.state = CircuitOpen
+	b.generation++
+	b.openedAt = now
+}
+
+func (b *circuitBreaker) close() {
+	logger.WithFields(logrus.Fields{
+		"provider": b.provider,
+		"outage":   time.Since(b.openedAt).Round(time.Second).String(),
+	}).Info("Webhook circuit breaker closed, provider recovered")
+
+	b.state = CircuitClosed
+	b.generation++
+	b.count = 0
+	b.next = 0
+	b.failures = 0
+	b.openedAt = time.Time{}
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// A half-open probe that is rate limited is never sent, so its slot must go
+// back to the breaker instead of leaving the circuit stuck half-open.
+func TestCircuitBreakerReleasesUnsentProbe(t *testing.T) {
+	breaker := newCircuitBreaker("primary", CircuitBreakerSettings{
+		Enabled:        true,
+		WindowSize:     1,
+		MinRequests:    1,
+		FailureRate:    0.5,
+		OpenDuration:   time.Millisecond,
+		HalfOpenProbes: 1,
+	})
+
+	ticket, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("allow() error = %v", err)
+	}
+	breaker.record(ticket, false)
+	time.Sleep(2 * time.Millisecond)
+
+	probe, err := breaker.allow()
+	if err != nil || !probe.probe {
+		t.Fatalf("allow() = %+v, %v, want a half-open probe", probe, err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second allow() error = %v, want ErrCircuitOpen while the probe is in flight", err)
+	}
+
+	breaker.release(probe)
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("allow() after release error = %v, want the probe slot back", err)
+	}
+}
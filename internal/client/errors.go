@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DeliveryError describes a failed delivery attempt and whether sending the
+// same message again may succeed. RetryAfter, when set, is how long until it
+// may.
+type DeliveryError struct {
+	StatusCode int
+	Retryable  bool
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+func retryableError(err error) error {
+	return &DeliveryError{Retryable: true, Err: err}
+}
+
+func permanentError(err error) error {
+	return &DeliveryError{Retryable: false, Err: err}
+}
+
+func statusError(statusCode int) error {
+	return &DeliveryError{
+		StatusCode: statusCode,
+		Retryable:  isRetryableStatus(statusCode),
+		Err:        fmt.Errorf("webhook request failed with status: %d", statusCode),
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	switch {
+	case statusCode >= 500:
+		return true
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether err is worth retrying. Errors that were not
+// classified by the client are treated as transient.
+func IsRetryable(err error) bool {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.Retryable
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRateLimited is wrapped by RateLimitError when a message is held back by
+// a provider or recipient rate limit.
+var ErrRateLimited = errors.New("webhook rate limit reached")
+
+// RateLimiter decides whether another request may be sent now. A denied
+// request comes with how long until it would be allowed.
+type RateLimiter interface {
+	AllowProvider(ctx context.Context, provider string) (bool, time.Duration)
+	AllowRecipient(ctx context.Context, recipient string) (bool, time.Duration)
+}
+
+// RateLimitError is returned instead of sending a message that is over a rate
+// limit. The message was not sent and can be sent again after RetryAfter.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
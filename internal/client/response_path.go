@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// errMessageIDNotFound is returned when the response has no usable value at
+// the configured path.
+var errMessageIDNotFound = errors.New("message ID not found in webhook response")
+
+// responsePath is a small subset of JSONPath used to find the provider
+// message ID in a response: an optional leading "$" followed by .name,
+// ["name"] and [index] steps, e.g. $.data.messages[0].id.
+type responsePath struct {
+	expression string
+	steps      []pathStep
+}
+
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parseResponsePath(expression string) (*responsePath, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(expression), "$")
+	path := &responsePath{expression: expression}
+
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+			fallthrough
+		case len(path.steps) == 0 && rest[0] != '[':
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid response path %q: empty field name", expression)
+			}
+			path.steps = append(path.steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid response path %q: unclosed [", expression)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if unquoted, err := strconv.Unquote(strings.ReplaceAll(inner, "'", "\"")); err == nil {
+				path.steps = append(path.steps, pathStep{key: unquoted})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid response path %q: %q is not a field name or index", expression, inner)
+			}
+			path.steps = append(path.steps, pathStep{index: index, isIndex: true})
+		default:
+			return nil, fmt.Errorf("invalid response path %q at %q", expression, rest)
+		}
+	}
+
+	if len(path.steps) == 0 {
+		return nil, fmt.Errorf("invalid response path %q: no fields", expression)
+	}
+	return path, nil
+}
+
+// extract returns the string or number found at the path in a JSON body. A
+// body that is not JSON is an error; a missing, empty or non-scalar value is
+// errMessageIDNotFound.
+func (p *responsePath) extract(body []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("failed to decode webhook response: %w", err)
+	}
+
+	for _, step := range p.steps {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			if step.isIndex {
+				return "", errMessageIDNotFound
+			}
+			value = node[step.key]
+		case []interface{}:
+			if !step.isIndex || step.index >= len(node) {
+				return "", errMessageIDNotFound
+			}
+			value = node[step.index]
+		default:
+			return "", errMessageIDNotFound
+		}
+	}
+
+	switch id := value.(type) {
+	case string:
+		if id == "" {
+			return "", errMessageIDNotFound
+		}
+		return id, nil
+	case json.Number:
+		return id.String(), nil
+	default:
+		return "", errMessageIDNotFound
+	}
+}
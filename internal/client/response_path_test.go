@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseResponsePath(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{"$.messageId", false},
+		{"messageId", false},
+		{"$.data.messages[0].id", false},
+		{`$["message-id"]`, false},
+		{"$['message-id']", false},
+		{"$[0]", false},
+		{"$", true},
+		{"", true},
+		{"$.", true},
+		{"$.data..id", true},
+		{"$.data[0", true},
+		{"$.data[-1]", true},
+		{"$.data[first]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := parseResponsePath(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseResponsePath(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResponsePathExtract(t *testing.T) {
+	tests := []struct {
+		name         string
+		expression   string
+		body         string
+		want         string
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{"top level field", "$.messageId", `{"messageId":"abc"}`, "abc", false, false},
+		{"nested field", "$.data.messages[1].id", `{"data":{"messages":[{"id":"a"},{"id":"b"}]}}`, "b", false, false},
+		{"quoted field", `$["message-id"]`, `{"message-id":"abc"}`, "abc", false, false},
+		{"number", "$.id", `{"id":12345678901234567890}`, "12345678901234567890", false, false},
+		{"array root", "$[0].sid", `[{"sid":"SM1"}]`, "SM1", false, false},
+		{"missing field", "$.messageId", `{"id":"abc"}`, "", true, false},
+		{"empty string", "$.messageId", `{"messageId":""}`, "", true, false},
+		{"object value", "$.data", `{"data":{"id":"abc"}}`, "", true, false},
+		{"index out of range", "$.ids[2]", `{"ids":["a"]}`, "", true, false},
+		{"index on object", "$.data[0]", `{"data":{"id":"abc"}}`, "", true, false},
+		{"field on scalar", "$.id.value", `{"id":"abc"}`, "", true, false},
+		{"not JSON", "$.messageId", `OK`, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := parseResponsePath(tt.expression)
+			if err != nil {
+				t.Fatalf("parseResponsePath(%q) error = %v", tt.expression, err)
+			}
+
+			got, err := path.extract([]byte(tt.body))
+			if errors.Is(err, errMessageIDNotFound) != tt.wantNotFound {
+				t.Fatalf("extract() error = %v, want not found %v", err, tt.wantNotFound)
+			}
+			if (err != nil && !tt.wantNotFound) != tt.wantErr {
+				t.Fatalf("extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
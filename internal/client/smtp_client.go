@@ -0,0 +1,273 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/pkg/logger"
+)
+
+type SMTPClient interface {
+	SendEmail(ctx context.Context, message entity.Message) (string, error)
+}
+
+type SMTPSettings struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	StartTLS string
+	Timeout  time.Duration
+	// TLSConfig overrides the configuration used for STARTTLS. When nil the
+	// server certificate is verified against Host.
+	TLSConfig *tls.Config
+}
+
+type smtpClient struct {
+	settings SMTPSettings
+	from     *mail.Address
+}
+
+func NewSMTPClient() (SMTPClient, error) {
+	emailConfig := config.AppSettings.Email
+	return NewSMTPClientWithSettings(SMTPSettings{
+		Host:     emailConfig.Host,
+		Port:     emailConfig.Port,
+		Username: emailConfig.Username,
+		Password: emailConfig.Password,
+		From:     emailConfig.From,
+		StartTLS: emailConfig.StartTLS,
+		Timeout:  emailConfig.Timeout,
+	})
+}
+
+func NewSMTPClientWithSettings(settings SMTPSettings) (SMTPClient, error) {
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", settings.From, err)
+	}
+	if settings.StartTLS == "" {
+		settings.StartTLS = config.StartTLSRequired
+	}
+	if settings.Timeout <= 0 {
+		settings.Timeout = 10 * time.Second
+	}
+
+	logger.WithFields(logrus.Fields{
+		"host":     settings.Host,
+		"port":     settings.Port,
+		"startTLS": settings.StartTLS,
+		"auth":     settings.Username != "",
+	}).Info("SMTP client configured")
+
+	return &smtpClient{settings: settings, from: from}, nil
+}
+
+// SendEmail delivers the message in a single SMTP session and returns the
+// Message-ID header it was sent with. 4xx replies and network errors are
+// retryable, 5xx replies are permanent.
+func (c *smtpClient) SendEmail(ctx context.Context, message entity.Message) (string, error) {
+	messageID := fmt.Sprintf("<%s@%s>", uuid.NewString(), domainOf(c.from.Address))
+	body, err := c.compose(message, messageID)
+	if err != nil {
+		return "", permanentError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.settings.Timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(c.settings.Host, strconv.Itoa(c.settings.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", retryableError(fmt.Errorf("failed to connect to SMTP server: %w", err))
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", retryableError(err)
+	}
+
+	if err := c.deliver(conn, message.To, body); err != nil {
+		logger.WithFields(logrus.Fields{
+			"messageID": message.ID.String(),
+			"to":        message.To,
+			"error":     err.Error(),
+		}).Debug("SMTP delivery failed")
+		return "", smtpError(err)
+	}
+
+	return messageID, nil
+}
+
+func (c *smtpClient) deliver(conn net.Conn, to string, body []byte) error {
+	session, err := smtp.NewClient(conn, c.settings.Host)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if err := c.startTLS(session); err != nil {
+		return err
+	}
+
+	if c.settings.Username != "" {
+		auth := smtp.PlainAuth("", c.settings.Username, c.settings.Password, c.settings.Host)
+		if err := session.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := session.Mail(c.from.Address); err != nil {
+		return err
+	}
+	if err := session.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := session.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	// The server has accepted the message once DATA is closed, so a failed
+	// QUIT must not fail the delivery and have the message sent again.
+	if err := session.Quit(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"host":  c.settings.Host,
+			"error": err.Error(),
+		}).Warn("SMTP QUIT failed after the message was accepted")
+	}
+	return nil
+}
+
+func (c *smtpClient) startTLS(session *smtp.Client) error {
+	if c.settings.StartTLS == config.StartTLSDisabled {
+		return nil
+	}
+
+	if ok, _ := session.Extension("STARTTLS"); !ok {
+		if c.settings.StartTLS == config.StartTLSRequired {
+			return permanentError(errors.New("SMTP server does not support STARTTLS"))
+		}
+		return nil
+	}
+
+	tlsConfig := c.settings.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: c.settings.Host, MinVersion: tls.VersionTLS12}
+	}
+	return session.StartTLS(tlsConfig)
+}
+
+// compose renders the message as MIME. A message with both a plain-text and
+// an HTML body is sent as multipart/alternative.
+func (c *smtpClient) compose(message entity.Message, messageID string) ([]byte, error) {
+	var buf bytes.Buffer
+	headers := []struct{ name, value string }{
+		{"From", c.from.String()},
+		{"To", message.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header.name, header.value)
+	}
+
+	switch {
+	case message.Content != "" && message.HTML != "":
+		parts := multipart.NewWriter(&buf)
+		fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+		for _, part := range []struct{ contentType, body string }{
+			{"text/plain", message.Content},
+			{"text/html", message.HTML},
+		} {
+			writer, err := parts.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {part.contentType + "; charset=utf-8"},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeQuotedPrintable(writer, part.body); err != nil {
+				return nil, err
+			}
+		}
+		if err := parts.Close(); err != nil {
+			return nil, err
+		}
+	case message.HTML != "":
+		buf.WriteString("Content-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, message.HTML); err != nil {
+			return nil, err
+		}
+	default:
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, message.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	writer := quotedprintable.NewWriter(w)
+	if _, err := writer.Write([]byte(body)); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
+
+func smtpError(err error) error {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return err
+	}
+
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		return &DeliveryError{
+			StatusCode: protocolErr.Code,
+			Retryable:  protocolErr.Code < 500,
+			Err:        fmt.Errorf("SMTP server replied %d: %s", protocolErr.Code, protocolErr.Msg),
+		}
+	}
+
+	return retryableError(err)
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/pkg/smtptest"
+)
+
+func newTestSMTPClient(t *testing.T, server *smtptest.Server, username, password string) SMTPClient {
+	t.Helper()
+	client, err := NewSMTPClientWithSettings(SMTPSettings{
+		Host:     server.Host(),
+		Port:     server.Port(),
+		Username: username,
+		Password: password,
+		From:     "Sender <sender@example.com>",
+		StartTLS: config.StartTLSDisabled,
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPClientWithSettings() error = %v", err)
+	}
+	return client
+}
+
+func testEmail() entity.Message {
+	return entity.Message{
+		ID:      uuid.New(),
+		To:      "user@example.com",
+		Subject: "Hello",
+		Content: "Hello there",
+		Channel: entity.ChannelEmail,
+	}
+}
+
+func TestSMTPClientSendEmail(t *testing.T) {
+	server := smtptest.NewServer()
+	defer server.Close()
+	server.RequireAuth("user", "secret")
+
+	messageID, err := newTestSMTPClient(t, server, "user", "secret").SendEmail(context.Background(), testEmail())
+	if err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+	if !strings.HasSuffix(messageID, "@example.com>") {
+		t.Errorf("SendEmail() message ID = %q, want one in the sender's domain", messageID)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(messages))
+	}
+	if got := messages[0]; got.From != "sender@example.com" || len(got.To) != 1 || got.To[0] != "user@example.com" {
+		t.Errorf("server received from %q to %v", got.From, got.To)
+	}
+	if !strings.Contains(messages[0].Data, "Message-ID: "+messageID) {
+		t.Errorf("message does not carry the returned Message-ID %s", messageID)
+	}
+}
+
+func TestSMTPClientErrorClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		command       string
+		reply         smtptest.Reply
+		wrongPassword bool
+		wantCode      int
+		wantRetryable bool
+	}{
+		{"unknown mailbox", "RCPT", smtptest.Reply{Code: 550, Message: "no such user"}, false, 550, false},
+		{"mailbox full", "RCPT", smtptest.Reply{Code: 452, Message: "mailbox full"}, false, 452, true},
+		{"sender rejected", "MAIL", smtptest.Reply{Code: 553, Message: "sender not allowed"}, false, 553, false},
+		{"greylisted", "DATA", smtptest.Reply{Code: 451, Message: "try again later"}, false, 451, true},
+		{"message rejected", "DATA", smtptest.Reply{Code: 554, Message: "rejected as spam"}, false, 554, false},
+		{"authentication failed", "", smtptest.Reply{}, true, 535, false},
+		{"authentication unavailable", "AUTH", smtptest.Reply{Code: 454, Message: "temporary failure"}, false, 454, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := smtptest.NewServer()
+			defer server.Close()
+			server.RequireAuth("user", "secret")
+			if tt.command != "" {
+				server.Reject(tt.command, tt.reply)
+			}
+
+			password := "secret"
+			if tt.wrongPassword {
+				password = "wrong"
+			}
+			_, err := newTestSMTPClient(t, server, "user", password).SendEmail(context.Background(), testEmail())
+
+			var deliveryErr *DeliveryError
+			if !errors.As(err, &deliveryErr) {
+				t.Fatalf("SendEmail() error = %v, want a DeliveryError", err)
+			}
+			if deliveryErr.StatusCode != tt.wantCode {
+				t.Errorf("StatusCode = %d, want %d", deliveryErr.StatusCode, tt.wantCode)
+			}
+			if IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", IsRetryable(err), tt.wantRetryable)
+			}
+			if len(server.Messages()) != 0 {
+				t.Errorf("server accepted a message that was rejected")
+			}
+		})
+	}
+}
+
+func TestSMTPClientConnectionRefusedIsRetryable(t *testing.T) {
+	server := smtptest.NewServer()
+	client := newTestSMTPClient(t, server, "", "")
+	server.Close()
+
+	_, err := client.SendEmail(context.Background(), testEmail())
+	if err == nil {
+		t.Fatal("SendEmail() succeeded without a server")
+	}
+	if !IsRetryable(err) {
+		t.Errorf("IsRetryable(%v) = false, want true", err)
+	}
+}
+
+// Once DATA is accepted the message is with the server, so a failed QUIT
+// must not fail the delivery and have it sent again.
+func TestSMTPClientIgnoresQuitFailureAfterData(t *testing.T) {
+	server := smtptest.NewServer()
+	defer server.Close()
+	server.Reject("QUIT", smtptest.Reply{Code: 421, Message: "closing connection"})
+
+	if _, err := newTestSMTPClient(t, server, "", "").SendEmail(context.Background(), testEmail()); err != nil {
+		t.Fatalf("SendEmail() error = %v, want success", err)
+	}
+	if len(server.Messages()) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(server.Messages()))
+	}
+}
+
+func TestSMTPClientStartTLSRequired(t *testing.T) {
+	server := smtptest.NewServer()
+	defer server.Close()
+
+	client, err := NewSMTPClientWithSettings(SMTPSettings{
+		Host:     server.Host(),
+		Port:     server.Port(),
+		From:     "sender@example.com",
+		StartTLS: config.StartTLSRequired,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPClientWithSettings() error = %v", err)
+	}
+
+	_, err = client.SendEmail(context.Background(), testEmail())
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("SendEmail() error = %v, want a permanent error", err)
+	}
+}
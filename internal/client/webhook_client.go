@@ -2,64 +2,279 @@ package client
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"auto-message-sender/internal/config"
 	"auto-message-sender/internal/entity"
-	"auto-message-sender/internal/model/request"
-	"auto-message-sender/internal/model/response"
 	"auto-message-sender/pkg/logger"
+	"auto-message-sender/pkg/signature"
 )
 
 type WebhookClient interface {
-	SendMessage(message entity.Message) (string, error)
+	SendMessage(ctx context.Context, message entity.Message) (Delivery, error)
+	// CircuitStates returns the circuit breaker state of every provider.
+	CircuitStates() map[string]CircuitState
+}
+
+// Delivery identifies the provider that accepted a message and the ID it
+// assigned to it.
+type Delivery struct {
+	Provider  string
+	MessageID string
 }
 
 type webhookClient struct {
-	client *http.Client
+	client    *http.Client
+	providers []*webhookProvider
+	routes    []webhookRoute
+	limiter   RateLimiter
+}
+
+type webhookRoute struct {
+	prefix    string
+	providers []*webhookProvider
+}
+
+// NewWebhookClient builds the configured providers and routes. A nil limiter
+// sends without rate limits.
+func NewWebhookClient(limiter RateLimiter) (WebhookClient, error) {
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	breakerConfig := config.AppSettings.CircuitBreaker
+	breakerSettings := CircuitBreakerSettings{
+		Enabled:        breakerConfig.Enabled,
+		WindowSize:     breakerConfig.WindowSize,
+		MinRequests:    breakerConfig.MinRequests,
+		FailureRate:    breakerConfig.FailureRate,
+		OpenDuration:   breakerConfig.OpenDuration,
+		HalfOpenProbes: breakerConfig.HalfOpenProbes,
+	}
+
+	c := &webhookClient{client: httpClient, limiter: limiter}
+	byName := make(map[string]*webhookProvider)
+	for _, settings := range config.AppSettings.Webhook.Providers {
+		provider, err := newWebhookProvider(settings, httpClient, breakerSettings)
+		if err != nil {
+			return nil, err
+		}
+		c.providers = append(c.providers, provider)
+		byName[provider.name] = provider
+
+		logger.WithFields(logrus.Fields{
+			"provider": provider.name,
+			"url":      provider.url,
+			"authType": settings.Auth.Type,
+		}).Info("Webhook provider configured")
+	}
+
+	for _, settings := range config.AppSettings.Webhook.Routes {
+		route := webhookRoute{prefix: settings.Prefix}
+		for _, name := range settings.Providers {
+			route.providers = append(route.providers, byName[name])
+		}
+		c.routes = append(c.routes, route)
+	}
+	// The longest prefix is the most specific route, so it is checked first.
+	sort.SliceStable(c.routes, func(i, j int) bool {
+		return len(c.routes[i].prefix) > len(c.routes[j].prefix)
+	})
+
+	logger.WithFields(logrus.Fields{
+		"providers":      len(c.providers),
+		"routes":         len(c.routes),
+		"signingSecrets": len(config.AppSettings.Webhook.Signing.Secrets),
+	}).Info("Webhook client configured")
+
+	return c, nil
+}
+
+func (c *webhookClient) CircuitStates() map[string]CircuitState {
+	states := make(map[string]CircuitState, len(c.providers))
+	for _, provider := range c.providers {
+		states[provider.name] = provider.breaker.snapshot()
+	}
+	return states
+}
+
+func (c *webhookClient) route(to string) (webhookRoute, bool) {
+	for _, route := range c.routes {
+		if strings.HasPrefix(to, route.prefix) {
+			return route, true
+		}
+	}
+	return webhookRoute{}, false
+}
+
+// SendMessage sends the message to the providers routed for its recipient,
+// in order, until one accepts it. A failed provider hands the message to the
+// next one, and a provider that is paused or over its rate limit is skipped
+// the same way. A provider is checked in that order, circuit breaker first,
+// so a paused provider never costs a token. When every provider is paused
+// by its circuit breaker the error wraps ErrCircuitOpen; when any of them,
+// or the recipient, is over its rate limit instead, it is a RateLimitError.
+// Either way it says how long until the first of them can take the message
+// again.
+func (c *webhookClient) SendMessage(ctx context.Context, message entity.Message) (Delivery, error) {
+	route, ok := c.route(message.To)
+	if !ok {
+		logger.WithFields(logrus.Fields{
+			"messageID": message.ID.String(),
+			"to":        message.To,
+		}).Error("No webhook provider is routed for the recipient")
+		return Delivery{}, permanentError(fmt.Errorf("no webhook provider is routed for %s", message.To))
+	}
+
+	var failures []string
+	// unavailable counts the providers that were paused or rate limited, so
+	// the message was not sent to them at all.
+	unavailable := 0
+	limited := false
+	var retryAfter time.Duration
+	recipientChecked := false
+	retryable := false
+	for i, provider := range route.providers {
+		ticket, err := provider.breaker.allow()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", provider.name, err.Error()))
+			unavailable++
+			if wait := c.pausedFor(provider); unavailable == 1 || wait < retryAfter {
+				retryAfter = wait
+			}
+			retryable = true
+			continue
+		}
+
+		if c.limiter != nil {
+			// The recipient is only charged once a provider can be called,
+			// so a message held back by paused providers keeps its allowance.
+			if !recipientChecked {
+				recipientChecked = true
+				if allowed, wait := c.limiter.AllowRecipient(ctx, message.To); !allowed {
+					provider.breaker.release(ticket)
+					logger.WithFields(logrus.Fields{
+						"messageID":  message.ID.String(),
+						"to":         message.To,
+						"retryAfter": wait.String(),
+					}).Info("Recipient is over its rate limit, message deferred")
+					return Delivery{}, &RateLimitError{
+						RetryAfter: wait,
+						Err:        fmt.Errorf("%w for recipient %s", ErrRateLimited, message.To),
+					}
+				}
+			}
+			if allowed, wait := c.limiter.AllowProvider(ctx, provider.name); !allowed {
+				provider.breaker.release(ticket)
+				failures = append(failures, fmt.Sprintf("%s: rate limit reached", provider.name))
+				unavailable++
+				if unavailable == 1 || wait < retryAfter {
+					retryAfter = wait
+				}
+				limited = true
+				logger.WithFields(logrus.Fields{
+					"messageID":  message.ID.String(),
+					"provider":   provider.name,
+					"retryAfter": wait.String(),
+				}).Debug("Webhook provider is over its rate limit")
+				continue
+			}
+		}
+
+		messageID, err := c.send(provider, ticket, message)
+		if err == nil {
+			if i > 0 {
+				logger.WithFields(logrus.Fields{
+					"messageID": message.ID.String(),
+					"provider":  provider.name,
+					"failed":    failures,
+				}).Info("Message sent by a fallback webhook provider")
+			}
+			return Delivery{Provider: provider.name, MessageID: messageID}, nil
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %s", provider.name, err.Error()))
+		retryable = retryable || IsRetryable(err)
+		if i < len(route.providers)-1 {
+			logger.WithFields(logrus.Fields{
+				"messageID": message.ID.String(),
+				"provider":  provider.name,
+				"next":      route.providers[i+1].name,
+				"error":     err.Error(),
+			}).Warn("Webhook provider failed, failing over to the next provider")
+		}
+	}
+
+	if unavailable == len(route.providers) {
+		if limited {
+			return Delivery{}, &RateLimitError{
+				RetryAfter: retryAfter,
+				Err:        fmt.Errorf("%w for every provider routed for %s", ErrRateLimited, route.prefix),
+			}
+		}
+		return Delivery{}, &DeliveryError{
+			Retryable:  true,
+			RetryAfter: retryAfter,
+			Err:        fmt.Errorf("%w for every provider routed for %s", ErrCircuitOpen, route.prefix),
+		}
+	}
+	return Delivery{}, &DeliveryError{
+		Retryable: retryable,
+		Err:       fmt.Errorf("every webhook provider failed: %s", strings.Join(failures, "; ")),
+	}
 }
 
-func NewWebhookClient() WebhookClient {
-	return &webhookClient{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+// pausedFor returns how long the circuit of a paused provider stays open.
+// A half-open circuit with all probes in flight settles within one request.
+func (c *webhookClient) pausedFor(provider *webhookProvider) time.Duration {
+	if wait := time.Until(provider.breaker.snapshot().RetryAt); wait > 0 {
+		return wait
 	}
+	return c.client.Timeout
 }
 
-func (c *webhookClient) SendMessage(message entity.Message) (string, error) {
-	webhookURL := config.AppSettings.Webhook.URL
+// send posts the message to one provider. The ticket from the provider's
+// circuit breaker is recorded with the outcome, or released when the request
+// is never sent.
+func (c *webhookClient) send(provider *webhookProvider, ticket circuitTicket, message entity.Message) (string, error) {
+	webhookURL := provider.url
+
+	// Timeouts, connection errors and retryable statuses count against the
+	// provider; any other response shows it is up.
+	requested := false
+	providerHealthy := false
+	defer func() {
+		if requested {
+			provider.breaker.record(ticket, providerHealthy)
+		} else {
+			provider.breaker.release(ticket)
+		}
+	}()
 
 	logger.WithFields(logrus.Fields{
 		"messageID": message.ID.String(),
 		"to":        message.To,
+		"provider":  provider.name,
 		"url":       webhookURL,
 	}).Debug("Preparing webhook request")
 
-	if webhookURL == "" {
-		logger.Error("Webhook URL is not configured")
-		return "", errors.New("webhook URL is not configured")
-	}
-
-	payload := request.WebhookRequest{
-		To:      message.To,
-		Content: message.Content,
-	}
-
-	payloadBytes, err := json.Marshal(payload)
+	payloadBytes, err := provider.payload(message)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"messageID": message.ID.String(),
+			"provider":  provider.name,
 			"error":     err.Error(),
-		}).Error("Failed to marshal webhook request")
-		return "", fmt.Errorf("failed to marshal webhook request: %w", err)
+		}).Error("Failed to render webhook request")
+		return "", permanentError(fmt.Errorf("failed to render webhook request: %w", err))
 	}
 
 	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payloadBytes))
@@ -69,10 +284,19 @@ func (c *webhookClient) SendMessage(message entity.Message) (string, error) {
 			"url":       webhookURL,
 			"error":     err.Error(),
 		}).Error("Failed to create webhook request")
-		return "", fmt.Errorf("failed to create webhook request: %w", err)
+		return "", retryableError(fmt.Errorf("failed to create webhook request: %w", err))
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", provider.contentType)
+	for name, value := range provider.headers {
+		req.Header.Set(name, value)
+	}
+	if err := provider.auth.Authenticate(req); err != nil {
+		return "", retryableError(fmt.Errorf("failed to authenticate webhook request: %w", err))
+	}
+	if secrets := config.AppSettings.Webhook.Signing.Secrets; len(secrets) > 0 {
+		signature.SignRequest(req, secrets, payloadBytes)
+	}
 
 	startTime := time.Now()
 	logger.WithFields(logrus.Fields{
@@ -80,6 +304,7 @@ func (c *webhookClient) SendMessage(message entity.Message) (string, error) {
 		"url":       webhookURL,
 	}).Debug("Sending webhook request")
 
+	requested = true
 	resp, err := c.client.Do(req)
 	requestDuration := time.Since(startTime)
 
@@ -90,9 +315,10 @@ func (c *webhookClient) SendMessage(message entity.Message) (string, error) {
 			"duration":  requestDuration.String(),
 			"error":     err.Error(),
 		}).Error("Failed to send webhook request")
-		return "", fmt.Errorf("failed to send webhook request: %w", err)
+		return "", retryableError(fmt.Errorf("failed to send webhook request: %w", err))
 	}
 	defer resp.Body.Close()
+	providerHealthy = !isRetryableStatus(resp.StatusCode)
 
 	logger.WithFields(logrus.Fields{
 		"messageID":  message.ID.String(),
@@ -100,38 +326,56 @@ func (c *webhookClient) SendMessage(message entity.Message) (string, error) {
 		"duration":   requestDuration.String(),
 	}).Debug("Webhook response received")
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusUnauthorized {
+		// A rejected cached token is dropped and the message retried with a
+		// fresh one; static credentials will not fix themselves.
+		if invalidator, ok := provider.auth.(tokenInvalidator); ok {
+			invalidator.Invalidate()
+			logger.WithField("messageID", message.ID.String()).Warn("Webhook rejected the access token, it will be refreshed")
+			return "", retryableError(fmt.Errorf("webhook rejected the access token"))
+		}
+	}
+
+	if !provider.succeeded(resp.StatusCode) {
 		logger.WithFields(logrus.Fields{
 			"messageID":  message.ID.String(),
+			"provider":   provider.name,
 			"statusCode": resp.StatusCode,
 			"duration":   requestDuration.String(),
-		}).Error("Webhook request failed with non-202 status code")
-		return "", fmt.Errorf("webhook request failed with status: %d", resp.StatusCode)
+		}).Error("Webhook request failed with an unexpected status code")
+		return "", statusError(resp.StatusCode)
 	}
 
+	// The provider has accepted the message at this point. A response that
+	// cannot be read or has no message ID does not change that, so the
+	// message is recorded as sent, although it cannot be matched to a
+	// delivery receipt, rather than failed or sent twice.
+	webhookMsgID := ""
 	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if err == nil {
+		webhookMsgID, err = provider.messageIDPath.extract(bodyBytes)
+	}
+	switch {
+	case errors.Is(err, errMessageIDNotFound):
 		logger.WithFields(logrus.Fields{
 			"messageID": message.ID.String(),
-			"error":     err.Error(),
-		}).Error("Failed to read webhook response body")
-		return "", fmt.Errorf("failed to read webhook response body: %w", err)
-	}
-
-	var webhookResponse response.WebhookResponse
-	if err := json.Unmarshal(bodyBytes, &webhookResponse); err != nil {
+			"provider":  provider.name,
+			"path":      provider.messageIDPath.expression,
+		}).Warn("Webhook response has no message ID at the configured path")
+	case err != nil:
 		logger.WithFields(logrus.Fields{
 			"messageID": message.ID.String(),
+			"provider":  provider.name,
 			"error":     err.Error(),
-		}).Error("Failed to decode webhook response")
-		return "", fmt.Errorf("failed to decode webhook response: %w", err)
+		}).Warn("Failed to read the message ID from the webhook response, recording the message as sent without it")
 	}
 
 	logger.WithFields(logrus.Fields{
 		"messageID":    message.ID.String(),
-		"webhookMsgID": webhookResponse.MessageID,
+		"provider":     provider.name,
+		"webhookMsgID": webhookMsgID,
 		"duration":     requestDuration.String(),
 	}).Info("Webhook request completed successfully")
 
-	return webhookResponse.MessageID, nil
+	return webhookMsgID, nil
 }
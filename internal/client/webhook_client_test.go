@@ -0,0 +1,379 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+)
+
+// testProvider is a webhook endpoint answering every request with status.
+type testProvider struct {
+	server   *httptest.Server
+	status   atomic.Int32
+	requests atomic.Int32
+}
+
+func newTestProvider(t *testing.T, status int) *testProvider {
+	t.Helper()
+	provider := &testProvider{}
+	provider.status.Store(int32(status))
+	provider.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(provider.status.Load()))
+		w.Write([]byte(`{"messageId":"` + r.Host + `"}`))
+	}))
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// fakeLimiter denies the named providers and recipients and records every
+// call in order.
+type fakeLimiter struct {
+	deniedProviders  map[string]bool
+	deniedRecipients map[string]bool
+	calls            []string
+}
+
+func (l *fakeLimiter) AllowProvider(ctx context.Context, provider string) (bool, time.Duration) {
+	l.calls = append(l.calls, "provider:"+provider)
+	if l.deniedProviders[provider] {
+		return false, 2 * time.Second
+	}
+	return true, 0
+}
+
+func (l *fakeLimiter) AllowRecipient(ctx context.Context, recipient string) (bool, time.Duration) {
+	l.calls = append(l.calls, "recipient:"+recipient)
+	if l.deniedRecipients[recipient] {
+		return false, 5 * time.Second
+	}
+	return true, 0
+}
+
+// setupWebhookClient configures the providers, in order, behind the given
+// routes, with a circuit breaker that opens on the first failure. With no
+// routes every recipient goes to all providers.
+func setupWebhookClient(t *testing.T, limiter RateLimiter, providers map[string]*testProvider, order []string, routes ...config.WebhookRoute) WebhookClient {
+	t.Helper()
+	saved := config.AppSettings
+	t.Cleanup(func() { config.AppSettings = saved })
+
+	config.AppSettings.Webhook.Providers = nil
+	for _, name := range order {
+		config.AppSettings.Webhook.Providers = append(config.AppSettings.Webhook.Providers, config.WebhookProvider{
+			Name: name,
+			URL:  providers[name].server.URL,
+		})
+	}
+	if len(routes) == 0 {
+		routes = []config.WebhookRoute{{Prefix: "+", Providers: order}}
+	}
+	config.AppSettings.Webhook.Routes = routes
+	config.AppSettings.Webhook.Signing.Secrets = nil
+	config.AppSettings.CircuitBreaker.Enabled = true
+	config.AppSettings.CircuitBreaker.WindowSize = 1
+	config.AppSettings.CircuitBreaker.MinRequests = 1
+	config.AppSettings.CircuitBreaker.FailureRate = 0.5
+	config.AppSettings.CircuitBreaker.OpenDuration = time.Minute
+	config.AppSettings.CircuitBreaker.HalfOpenProbes = 1
+
+	client, err := NewWebhookClient(limiter)
+	if err != nil {
+		t.Fatalf("NewWebhookClient() error = %v", err)
+	}
+	return client
+}
+
+func testSMS() entity.Message {
+	return entity.Message{ID: uuid.New(), To: "+905551234567", Content: "Hello", Channel: entity.ChannelWebhook}
+}
+
+func TestWebhookClientFailover(t *testing.T) {
+	tests := []struct {
+		name          string
+		primary       int
+		backup        int
+		wantProvider  string
+		wantErr       bool
+		wantRetryable bool
+		wantBackup    int32
+	}{
+		{"primary accepts", http.StatusOK, http.StatusOK, "primary", false, false, 0},
+		{"primary unavailable", http.StatusServiceUnavailable, http.StatusOK, "backup", false, false, 1},
+		{"primary rejects", http.StatusBadRequest, http.StatusOK, "backup", false, false, 1},
+		{"both unavailable", http.StatusServiceUnavailable, http.StatusBadGateway, "", true, true, 1},
+		{"both reject", http.StatusBadRequest, http.StatusUnprocessableEntity, "", true, false, 1},
+		{"one unavailable one rejects", http.StatusServiceUnavailable, http.StatusBadRequest, "", true, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := map[string]*testProvider{
+				"primary": newTestProvider(t, tt.primary),
+				"backup":  newTestProvider(t, tt.backup),
+			}
+			client := setupWebhookClient(t, nil, providers, []string{"primary", "backup"})
+
+			delivery, err := client.SendMessage(context.Background(), testSMS())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && IsRetryable(err) != tt.wantRetryable {
+				t.Errorf("IsRetryable() = %v, want %v", IsRetryable(err), tt.wantRetryable)
+			}
+			if delivery.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", delivery.Provider, tt.wantProvider)
+			}
+			if tt.wantProvider != "" {
+				if host := providers[tt.wantProvider].server.Listener.Addr().String(); delivery.MessageID != host {
+					t.Errorf("MessageID = %q, want %q", delivery.MessageID, host)
+				}
+			}
+			if got := providers["backup"].requests.Load(); got != tt.wantBackup {
+				t.Errorf("backup received %d requests, want %d", got, tt.wantBackup)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRoutesByLongestPrefix(t *testing.T) {
+	providers := map[string]*testProvider{
+		"global": newTestProvider(t, http.StatusOK),
+		"turkey": newTestProvider(t, http.StatusOK),
+	}
+	client := setupWebhookClient(t, nil, providers, []string{"global", "turkey"},
+		config.WebhookRoute{Prefix: "+", Providers: []string{"global"}},
+		config.WebhookRoute{Prefix: "+90", Providers: []string{"turkey"}},
+	)
+
+	tests := []struct {
+		to           string
+		wantProvider string
+	}{
+		{"+905551234567", "turkey"},
+		{"+445551234567", "global"},
+	}
+	for _, tt := range tests {
+		message := testSMS()
+		message.To = tt.to
+		delivery, err := client.SendMessage(context.Background(), message)
+		if err != nil {
+			t.Fatalf("SendMessage(%s) error = %v", tt.to, err)
+		}
+		if delivery.Provider != tt.wantProvider {
+			t.Errorf("SendMessage(%s) Provider = %q, want %q", tt.to, delivery.Provider, tt.wantProvider)
+		}
+	}
+}
+
+func TestWebhookClientWithoutRoute(t *testing.T) {
+	providers := map[string]*testProvider{"turkey": newTestProvider(t, http.StatusOK)}
+	client := setupWebhookClient(t, nil, providers, []string{"turkey"},
+		config.WebhookRoute{Prefix: "+90", Providers: []string{"turkey"}},
+	)
+
+	message := testSMS()
+	message.To = "+445551234567"
+	_, err := client.SendMessage(context.Background(), message)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("SendMessage() error = %v, want a permanent error", err)
+	}
+	if got := providers["turkey"].requests.Load(); got != 0 {
+		t.Errorf("turkey received %d requests, want 0", got)
+	}
+}
+
+func TestWebhookClientSkipsOpenCircuit(t *testing.T) {
+	providers := map[string]*testProvider{
+		"primary": newTestProvider(t, http.StatusServiceUnavailable),
+		"backup":  newTestProvider(t, http.StatusOK),
+	}
+	limiter := &fakeLimiter{}
+	client := setupWebhookClient(t, limiter, providers, []string{"primary", "backup"})
+
+	// The failed request opens the primary's circuit.
+	if _, err := client.SendMessage(context.Background(), testSMS()); err != nil {
+		t.Fatalf("first SendMessage() error = %v", err)
+	}
+	if state := client.CircuitStates()["primary"].State; state != CircuitOpen {
+		t.Fatalf("primary circuit is %s, want %s", state, CircuitOpen)
+	}
+
+	limiter.calls = nil
+	delivery, err := client.SendMessage(context.Background(), testSMS())
+	if err != nil {
+		t.Fatalf("second SendMessage() error = %v", err)
+	}
+	if delivery.Provider != "backup" {
+		t.Errorf("Provider = %q, want backup", delivery.Provider)
+	}
+	if got := providers["primary"].requests.Load(); got != 1 {
+		t.Errorf("primary received %d requests while its circuit was open, want 1", got)
+	}
+	// A paused provider must not cost a rate limit token.
+	want := []string{"recipient:+905551234567", "provider:backup"}
+	if !reflect.DeepEqual(limiter.calls, want) {
+		t.Errorf("limiter calls = %v, want %v", limiter.calls, want)
+	}
+}
+
+func TestWebhookClientAllCircuitsOpen(t *testing.T) {
+	providers := map[string]*testProvider{
+		"primary": newTestProvider(t, http.StatusServiceUnavailable),
+		"backup":  newTestProvider(t, http.StatusServiceUnavailable),
+	}
+	limiter := &fakeLimiter{}
+	client := setupWebhookClient(t, limiter, providers, []string{"primary", "backup"})
+
+	if _, err := client.SendMessage(context.Background(), testSMS()); err == nil {
+		t.Fatal("first SendMessage() succeeded, want both providers to fail")
+	}
+
+	limiter.calls = nil
+	_, err := client.SendMessage(context.Background(), testSMS())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("SendMessage() error = %v, want ErrCircuitOpen", err)
+	}
+	var deliveryErr *DeliveryError
+	if !errors.As(err, &deliveryErr) || !deliveryErr.Retryable {
+		t.Fatalf("SendMessage() error = %v, want a retryable DeliveryError", err)
+	}
+	if deliveryErr.RetryAfter <= 0 || deliveryErr.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %s, want the time until the circuits half-open", deliveryErr.RetryAfter)
+	}
+	if len(limiter.calls) != 0 {
+		t.Errorf("limiter calls = %v, want none while every circuit is open", limiter.calls)
+	}
+	for name, provider := range providers {
+		if got := provider.requests.Load(); got != 1 {
+			t.Errorf("%s received %d requests, want 1", name, got)
+		}
+	}
+}
+
+func TestWebhookClientRateLimits(t *testing.T) {
+	tests := []struct {
+		name             string
+		limiter          *fakeLimiter
+		wantProvider     string
+		wantRetryAfter   time.Duration
+		wantCalls        []string
+		wantPrimary      int32
+		wantBackup       int32
+		wantRateLimitErr bool
+	}{
+		{
+			name:         "within limits",
+			limiter:      &fakeLimiter{},
+			wantProvider: "primary",
+			wantCalls:    []string{"recipient:+905551234567", "provider:primary"},
+			wantPrimary:  1,
+		},
+		{
+			name:         "primary over its limit",
+			limiter:      &fakeLimiter{deniedProviders: map[string]bool{"primary": true}},
+			wantProvider: "backup",
+			wantCalls:    []string{"recipient:+905551234567", "provider:primary", "provider:backup"},
+			wantBackup:   1,
+		},
+		{
+			name:             "every provider over its limit",
+			limiter:          &fakeLimiter{deniedProviders: map[string]bool{"primary": true, "backup": true}},
+			wantRetryAfter:   2 * time.Second,
+			wantCalls:        []string{"recipient:+905551234567", "provider:primary", "provider:backup"},
+			wantRateLimitErr: true,
+		},
+		{
+			name:             "recipient over its limit",
+			limiter:          &fakeLimiter{deniedRecipients: map[string]bool{"+905551234567": true}},
+			wantRetryAfter:   5 * time.Second,
+			wantCalls:        []string{"recipient:+905551234567"},
+			wantRateLimitErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := map[string]*testProvider{
+				"primary": newTestProvider(t, http.StatusOK),
+				"backup":  newTestProvider(t, http.StatusOK),
+			}
+			client := setupWebhookClient(t, tt.limiter, providers, []string{"primary", "backup"})
+
+			delivery, err := client.SendMessage(context.Background(), testSMS())
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) != tt.wantRateLimitErr {
+				t.Fatalf("SendMessage() error = %v, want RateLimitError %v", err, tt.wantRateLimitErr)
+			}
+			if tt.wantRateLimitErr {
+				if !errors.Is(err, ErrRateLimited) {
+					t.Errorf("SendMessage() error = %v, want ErrRateLimited", err)
+				}
+				if rateLimitErr.RetryAfter != tt.wantRetryAfter {
+					t.Errorf("RetryAfter = %s, want %s", rateLimitErr.RetryAfter, tt.wantRetryAfter)
+				}
+			}
+			if delivery.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", delivery.Provider, tt.wantProvider)
+			}
+			if !reflect.DeepEqual(tt.limiter.calls, tt.wantCalls) {
+				t.Errorf("limiter calls = %v, want %v", tt.limiter.calls, tt.wantCalls)
+			}
+			if got := providers["primary"].requests.Load(); got != tt.wantPrimary {
+				t.Errorf("primary received %d requests, want %d", got, tt.wantPrimary)
+			}
+			if got := providers["backup"].requests.Load(); got != tt.wantBackup {
+				t.Errorf("backup received %d requests, want %d", got, tt.wantBackup)
+			}
+		})
+	}
+}
+
+// A provider that accepted the message but answered with a body the message
+// ID cannot be read from must not have the message failed or sent again.
+func TestWebhookClientAcceptsUnreadableResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not JSON", "OK"},
+		{"no message ID", `{"status":"queued"}`},
+		{"empty body", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			providers := map[string]*testProvider{
+				"primary": {server: server},
+				"backup":  newTestProvider(t, http.StatusOK),
+			}
+			client := setupWebhookClient(t, nil, providers, []string{"primary", "backup"})
+
+			delivery, err := client.SendMessage(context.Background(), testSMS())
+			if err != nil {
+				t.Fatalf("SendMessage() error = %v, want the message recorded as sent", err)
+			}
+			if delivery.Provider != "primary" || delivery.MessageID != "" {
+				t.Errorf("SendMessage() = %+v, want primary with no message ID", delivery)
+			}
+			if got := providers["backup"].requests.Load(); got != 0 {
+				t.Errorf("backup received %d requests, want 0", got)
+			}
+		})
+	}
+}
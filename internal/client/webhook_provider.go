@@ -0,0 +1,113 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"text/template"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/model/request"
+)
+
+const defaultMessageIDPath = "$.messageId"
+
+// webhookProvider is one provider endpoint with its own credentials, request
+// and response mapping and circuit breaker, so an outage at one provider does
+// not pause the others.
+type webhookProvider struct {
+	name          string
+	url           string
+	auth          Authenticator
+	template      *template.Template
+	contentType   string
+	headers       map[string]string
+	successStatus []int
+	messageIDPath *responsePath
+	breaker       *circuitBreaker
+}
+
+// payloadFuncs are available to provider templates. json renders a value as a
+// JSON literal, e.g. {"msisdn": {{json .To}}}; form templates can use the
+// built-in urlquery, e.g. to={{urlquery .To}}.
+var payloadFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+func newWebhookProvider(settings config.WebhookProvider, httpClient *http.Client, breakerSettings CircuitBreakerSettings) (*webhookProvider, error) {
+	provider := &webhookProvider{
+		name:          settings.Name,
+		url:           settings.URL,
+		auth:          NewAuthenticator(settings.Auth, httpClient),
+		contentType:   contentType(settings.ContentType),
+		headers:       settings.Headers,
+		successStatus: settings.SuccessStatus,
+		breaker:       newCircuitBreaker(settings.Name, breakerSettings),
+	}
+	if len(provider.successStatus) == 0 {
+		provider.successStatus = []int{http.StatusOK}
+	}
+
+	messageIDPath := settings.MessageIDPath
+	if messageIDPath == "" {
+		messageIDPath = defaultMessageIDPath
+	}
+	path, err := parseResponsePath(messageIDPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message_id_path for webhook provider %s: %w", settings.Name, err)
+	}
+	provider.messageIDPath = path
+
+	if settings.Template != "" {
+		tmpl, err := template.New(settings.Name).Funcs(payloadFuncs).Parse(settings.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for webhook provider %s: %w", settings.Name, err)
+		}
+		provider.template = tmpl
+	}
+
+	return provider, nil
+}
+
+func contentType(setting string) string {
+	switch setting {
+	case "", config.ContentTypeJSON:
+		return "application/json"
+	case config.ContentTypeForm:
+		return "application/x-www-form-urlencoded"
+	default:
+		return setting
+	}
+}
+
+// payload renders the request body for message. The template sees the
+// message entity, so {{.To}}, {{.Content}} and {{.ID}} are available. A JSON
+// body is checked before it is sent so a broken template fails the message
+// instead of reaching the provider.
+func (p *webhookProvider) payload(message entity.Message) ([]byte, error) {
+	if p.template == nil {
+		return json.Marshal(request.WebhookRequest{
+			To:      message.To,
+			Content: message.Content,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := p.template.Execute(&buf, message); err != nil {
+		return nil, err
+	}
+	if p.contentType == "application/json" && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("template of webhook provider %s rendered invalid JSON", p.name)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *webhookProvider) succeeded(statusCode int) bool {
+	return slices.Contains(p.successStatus, statusCode)
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+)
+
+func TestWebhookProviderPayload(t *testing.T) {
+	message := entity.Message{
+		ID:      uuid.MustParse("6f1c2a9e-3f4b-4c5d-8e7f-0a1b2c3d4e5f"),
+		To:      "+905551234567",
+		Content: `Say "hi" & bye`,
+	}
+
+	tests := []struct {
+		name        string
+		settings    config.WebhookProvider
+		want        string
+		wantType    string
+		wantErr     bool
+		wantInitErr bool
+	}{
+		{
+			name:     "default body",
+			settings: config.WebhookProvider{},
+			want:     `{"to":"+905551234567","content":"Say \"hi\" \u0026 bye"}`,
+			wantType: "application/json",
+		},
+		{
+			name: "json template",
+			settings: config.WebhookProvider{
+				Template: `{"msisdn":{{json .To}},"text":{{json .Content}},"ref":"{{.ID}}"}`,
+			},
+			want:     `{"msisdn":"+905551234567","text":"Say \"hi\" \u0026 bye","ref":"6f1c2a9e-3f4b-4c5d-8e7f-0a1b2c3d4e5f"}`,
+			wantType: "application/json",
+		},
+		{
+			name: "form template",
+			settings: config.WebhookProvider{
+				ContentType: config.ContentTypeForm,
+				Template:    `to={{urlquery .To}}&body={{urlquery .Content}}`,
+			},
+			want:     `to=%2B905551234567&body=Say+%22hi%22+%26+bye`,
+			wantType: "application/x-www-form-urlencoded",
+		},
+		{
+			name: "template rendering invalid JSON",
+			settings: config.WebhookProvider{
+				Template: `{"text":"{{.Content}}"}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "template with an unknown field",
+			settings: config.WebhookProvider{
+				Template: `{"text":{{json .Body}}}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "template that does not parse",
+			settings: config.WebhookProvider{
+				Template: `{"text":{{json .Content}`,
+			},
+			wantInitErr: true,
+		},
+		{
+			name: "invalid message ID path",
+			settings: config.WebhookProvider{
+				MessageIDPath: "$.data[",
+			},
+			wantInitErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.settings.Name = "test"
+			provider, err := newWebhookProvider(tt.settings, http.DefaultClient, CircuitBreakerSettings{})
+			if (err != nil) != tt.wantInitErr {
+				t.Fatalf("newWebhookProvider() error = %v, wantInitErr %v", err, tt.wantInitErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got, err := provider.payload(message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("payload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("payload() = %s, want %s", got, tt.want)
+			}
+			if provider.contentType != tt.wantType {
+				t.Errorf("contentType = %q, want %q", provider.contentType, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestWebhookProviderSucceeded(t *testing.T) {
+	defaults, err := newWebhookProvider(config.WebhookProvider{Name: "default"}, http.DefaultClient, CircuitBreakerSettings{})
+	if err != nil {
+		t.Fatalf("newWebhookProvider() error = %v", err)
+	}
+	custom, err := newWebhookProvider(config.WebhookProvider{
+		Name:          "custom",
+		SuccessStatus: []int{http.StatusCreated, http.StatusAccepted},
+	}, http.DefaultClient, CircuitBreakerSettings{})
+	if err != nil {
+		t.Fatalf("newWebhookProvider() error = %v", err)
+	}
+
+	tests := []struct {
+		provider *webhookProvider
+		status   int
+		want     bool
+	}{
+		{defaults, http.StatusOK, true},
+		{defaults, http.StatusAccepted, false},
+		{custom, http.StatusAccepted, true},
+		{custom, http.StatusCreated, true},
+		{custom, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		if got := tt.provider.succeeded(tt.status); got != tt.want {
+			t.Errorf("%s succeeded(%d) = %v, want %v", tt.provider.name, tt.status, got, tt.want)
+		}
+	}
+}
+
+// A provider is called with its own body, content type and headers, and the
+// message ID is read from its own response shape.
+func TestWebhookClientUsesProviderMapping(t *testing.T) {
+	var gotBody, gotType, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotType = r.Header.Get("Content-Type")
+		gotHeader = r.Header.Get("X-Account")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"data":{"messages":[{"sid":"SM42"}]}}`))
+	}))
+	defer server.Close()
+
+	saved := config.AppSettings
+	defer func() { config.AppSettings = saved }()
+	config.AppSettings.Webhook.Providers = []config.WebhookProvider{{
+		Name:          "sms",
+		URL:           server.URL,
+		ContentType:   config.ContentTypeForm,
+		Template:      `to={{urlquery .To}}&body={{urlquery .Content}}`,
+		Headers:       map[string]string{"X-Account": "acme"},
+		SuccessStatus: []int{http.StatusAccepted},
+		MessageIDPath: "$.data.messages[0].sid",
+	}}
+	config.AppSettings.Webhook.Routes = []config.WebhookRoute{{Prefix: "+", Providers: []string{"sms"}}}
+	config.AppSettings.Webhook.Signing.Secrets = nil
+	config.AppSettings.CircuitBreaker.Enabled = false
+
+	client, err := NewWebhookClient(nil)
+	if err != nil {
+		t.Fatalf("NewWebhookClient(nil) error = %v", err)
+	}
+
+	delivery, err := client.SendMessage(context.Background(), testSMS())
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if delivery.MessageID != "SM42" {
+		t.Errorf("MessageID = %q, want SM42", delivery.MessageID)
+	}
+	if gotBody != "to=%2B905551234567&body=Hello" {
+		t.Errorf("request body = %q", gotBody)
+	}
+	if gotType != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q", gotType)
+	}
+	if gotHeader != "acme" {
+		t.Errorf("X-Account = %q, want acme", gotHeader)
+	}
+}
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
+
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/validator"
 )
 
 type Configuration struct {
@@ -22,18 +29,203 @@ type Configuration struct {
 		SSLMode  string `mapstructure:"ssl_mode"`
 	} `mapstructure:"database"`
 
+	Channels struct {
+		// Default is the channel used for messages that do not name one.
+		Default string `mapstructure:"default"`
+	} `mapstructure:"channels"`
+
 	Webhook struct {
-		URL string `mapstructure:"url"`
+		Enabled bool        `mapstructure:"enabled"`
+		URL     string      `mapstructure:"url"`
+		Auth    WebhookAuth `mapstructure:"auth"`
+		Signing struct {
+			// Secrets are the active HMAC signing secrets. Requests are signed
+			// with each of them so a secret can be rotated without downtime.
+			Secrets     []string `mapstructure:"secrets"`
+			SecretsFile string   `mapstructure:"secrets_file"`
+		} `mapstructure:"signing"`
+		// AuthKeyName and AuthKey are the legacy static header settings. They
+		// are used as a header auth when auth.type is not set.
+		AuthKeyName string `mapstructure:"auth_key_name"`
+		AuthKey     string `mapstructure:"auth_key"`
+		// Providers and Routes pick the provider by the recipient's prefix.
+		// Without providers, url and auth form a single provider named
+		// "default" that every recipient is routed to.
+		Providers []WebhookProvider `mapstructure:"providers"`
+		Routes    []WebhookRoute    `mapstructure:"routes"`
 	} `mapstructure:"webhook"`
 
+	// File writes messages as JSON lines to a file, or to stdout, instead of
+	// delivering them. It is meant for local development.
+	File struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Path    string `mapstructure:"path"`
+	} `mapstructure:"file"`
+
+	Email struct {
+		Enabled      bool          `mapstructure:"enabled"`
+		Host         string        `mapstructure:"host"`
+		Port         int           `mapstructure:"port"`
+		Username     string        `mapstructure:"username"`
+		Password     string        `mapstructure:"password"`
+		PasswordFile string        `mapstructure:"password_file"`
+		From         string        `mapstructure:"from"`
+		StartTLS     string        `mapstructure:"starttls"`
+		Timeout      time.Duration `mapstructure:"timeout"`
+	} `mapstructure:"email"`
+
 	Redis struct {
 		Host string `mapstructure:"host"`
 		Port string `mapstructure:"port"`
 	} `mapstructure:"redis"`
 
+	Dispatcher struct {
+		Interval        time.Duration  `mapstructure:"interval"`
+		BatchSize       int            `mapstructure:"batch_size"`
+		MaxPerTick      int            `mapstructure:"max_per_tick"`
+		Workers         int            `mapstructure:"workers"`
+		InstanceID      string         `mapstructure:"instance_id"`
+		LeaseDuration   time.Duration  `mapstructure:"lease_duration"`
+		PriorityWeights map[string]int `mapstructure:"priority_weights"`
+	} `mapstructure:"dispatcher"`
+
+	Retry struct {
+		BaseDelay   time.Duration `mapstructure:"base_delay"`
+		MaxDelay    time.Duration `mapstructure:"max_delay"`
+		Multiplier  float64       `mapstructure:"multiplier"`
+		Jitter      float64       `mapstructure:"jitter"`
+		MaxAttempts int           `mapstructure:"max_attempts"`
+	} `mapstructure:"retry"`
+
+	Scheduling struct {
+		MaxAhead time.Duration `mapstructure:"max_ahead"`
+	} `mapstructure:"scheduling"`
+
+	Idempotency struct {
+		Retention time.Duration `mapstructure:"retention"`
+		// ReservationTimeout bounds how long a key stays reserved by a request
+		// that has not completed, so a crash does not block it for Retention.
+		ReservationTimeout time.Duration `mapstructure:"reservation_timeout"`
+	} `mapstructure:"idempotency"`
+
+	Batch struct {
+		MaxItems   int `mapstructure:"max_items"`
+		InsertSize int `mapstructure:"insert_size"`
+	} `mapstructure:"batch"`
+
+	Import struct {
+		ReportRetention time.Duration `mapstructure:"report_retention"`
+		DedupWindow     int           `mapstructure:"dedup_window"`
+	} `mapstructure:"import"`
+
+	CircuitBreaker struct {
+		Enabled        bool          `mapstructure:"enabled"`
+		WindowSize     int           `mapstructure:"window_size"`
+		MinRequests    int           `mapstructure:"min_requests"`
+		FailureRate    float64       `mapstructure:"failure_rate"`
+		OpenDuration   time.Duration `mapstructure:"open_duration"`
+		HalfOpenProbes int           `mapstructure:"half_open_probes"`
+	} `mapstructure:"circuit_breaker"`
+
+	// RateLimit throttles outbound webhook requests per provider and per
+	// recipient with token buckets kept in Redis, so the limits hold across
+	// replicas. Provider applies to every provider without its own rate_limit.
+	RateLimit struct {
+		Enabled   bool      `mapstructure:"enabled"`
+		Provider  RateLimit `mapstructure:"provider"`
+		Recipient RateLimit `mapstructure:"recipient"`
+	} `mapstructure:"rate_limit"`
+
+	Callback struct {
+		Secret     string        `mapstructure:"secret"`
+		SecretFile string        `mapstructure:"secret_file"`
+		MaxSkew    time.Duration `mapstructure:"max_skew"`
+	} `mapstructure:"callback"`
+
 	Environment string
 }
 
+const (
+	WebhookAuthNone   = "none"
+	WebhookAuthHeader = "header"
+	WebhookAuthBearer = "bearer"
+	WebhookAuthBasic  = "basic"
+	WebhookAuthOAuth2 = "oauth2"
+)
+
+// WebhookAuth configures how outbound webhook requests authenticate. Every
+// secret can be given inline, usually through an environment variable such as
+// WEBHOOK_AUTH_TOKEN, or as a path in the matching _file setting.
+type WebhookAuth struct {
+	Type string `mapstructure:"type"`
+
+	HeaderName      string `mapstructure:"header_name"`
+	HeaderValue     string `mapstructure:"header_value"`
+	HeaderValueFile string `mapstructure:"header_value_file"`
+
+	Token     string `mapstructure:"token"`
+	TokenFile string `mapstructure:"token_file"`
+
+	Username     string `mapstructure:"username"`
+	Password     string `mapstructure:"password"`
+	PasswordFile string `mapstructure:"password_file"`
+
+	TokenURL         string   `mapstructure:"token_url"`
+	ClientID         string   `mapstructure:"client_id"`
+	ClientSecret     string   `mapstructure:"client_secret"`
+	ClientSecretFile string   `mapstructure:"client_secret_file"`
+	Scopes           []string `mapstructure:"scopes"`
+}
+
+const (
+	StartTLSRequired      = "required"
+	StartTLSOpportunistic = "opportunistic"
+	StartTLSDisabled      = "disabled"
+)
+
+// DefaultWebhookProvider is the name of the provider built from the legacy
+// webhook.url and webhook.auth settings.
+const DefaultWebhookProvider = "default"
+
+const (
+	ContentTypeJSON = "json"
+	ContentTypeForm = "form"
+)
+
+// WebhookProvider is one provider endpoint. Template is a Go text/template
+// rendering the request body from the message; the default posts
+// {"to", "content"}. ContentType is json, form or a literal MIME type.
+// The request succeeds on any of SuccessStatus, and MessageIDPath, a
+// JSONPath-like expression such as $.data.id, locates the provider message ID
+// in the response. RateLimit overrides rate_limit.provider for this provider.
+type WebhookProvider struct {
+	Name          string            `mapstructure:"name"`
+	URL           string            `mapstructure:"url"`
+	Auth          WebhookAuth       `mapstructure:"auth"`
+	Template      string            `mapstructure:"template"`
+	ContentType   string            `mapstructure:"content_type"`
+	Headers       map[string]string `mapstructure:"headers"`
+	SuccessStatus []int             `mapstructure:"success_status"`
+	MessageIDPath string            `mapstructure:"message_id_path"`
+	RateLimit     RateLimit         `mapstructure:"rate_limit"`
+}
+
+// RateLimit allows Limit requests per Period with bursts of up to Burst. A
+// zero Limit means no limit; Burst defaults to Limit.
+type RateLimit struct {
+	Limit  int           `mapstructure:"limit"`
+	Period time.Duration `mapstructure:"period"`
+	Burst  int           `mapstructure:"burst"`
+}
+
+// WebhookRoute sends recipients starting with Prefix to Providers, trying
+// them in order until one accepts the message. The longest matching prefix
+// wins; "+" matches every number.
+type WebhookRoute struct {
+	Prefix    string   `mapstructure:"prefix"`
+	Providers []string `mapstructure:"providers"`
+}
+
 var AppSettings Configuration
 
 func LoadSettings() error {
@@ -72,11 +264,416 @@ func LoadSettings() error {
 	viper.SetDefault("database.password", "postgres")
 	viper.SetDefault("database.name", "auto_message_sender")
 	viper.SetDefault("database.ssl_mode", "disable")
+	viper.SetDefault("channels.default", entity.ChannelWebhook)
+	viper.SetDefault("webhook.enabled", true)
 	viper.SetDefault("webhook.url", "https://webhook.site/c3f13233-1ed4-429e-9649-8133b3b9c9cd")
 	viper.SetDefault("webhook.auth_key_name", "x-ins-auth-key")
-	viper.SetDefault("webhook.auth_key", "INS.me1x9uMcyYGlhKKQVPoc.bO3j9aZwRTOcA2Ywo")
+	viper.SetDefault("webhook.auth_key", "")
+	viper.SetDefault("webhook.auth.type", "")
+	viper.SetDefault("webhook.auth.header_name", "")
+	viper.SetDefault("webhook.auth.header_value", "")
+	viper.SetDefault("webhook.auth.header_value_file", "")
+	viper.SetDefault("webhook.auth.token", "")
+	viper.SetDefault("webhook.auth.token_file", "")
+	viper.SetDefault("webhook.auth.username", "")
+	viper.SetDefault("webhook.auth.password", "")
+	viper.SetDefault("webhook.auth.password_file", "")
+	viper.SetDefault("webhook.auth.token_url", "")
+	viper.SetDefault("webhook.auth.client_id", "")
+	viper.SetDefault("webhook.auth.client_secret", "")
+	viper.SetDefault("webhook.auth.client_secret_file", "")
+	viper.SetDefault("webhook.auth.scopes", []string{})
+	viper.SetDefault("webhook.signing.secrets", []string{})
+	viper.SetDefault("webhook.signing.secrets_file", "")
+	viper.SetDefault("webhook.providers", []WebhookProvider{})
+	viper.SetDefault("webhook.routes", []WebhookRoute{})
+	viper.SetDefault("file.enabled", false)
+	viper.SetDefault("file.path", "stdout")
+	viper.SetDefault("email.enabled", false)
+	viper.SetDefault("email.host", "localhost")
+	viper.SetDefault("email.port", 587)
+	viper.SetDefault("email.username", "")
+	viper.SetDefault("email.password", "")
+	viper.SetDefault("email.password_file", "")
+	viper.SetDefault("email.from", "")
+	viper.SetDefault("email.starttls", StartTLSRequired)
+	viper.SetDefault("email.timeout", "10s")
 	viper.SetDefault("redis.host", "localhost")
 	viper.SetDefault("redis.port", "6379")
+	viper.SetDefault("dispatcher.interval", "2m")
+	viper.SetDefault("dispatcher.batch_size", 2)
+	viper.SetDefault("dispatcher.max_per_tick", 2)
+	viper.SetDefault("dispatcher.workers", 4)
+	viper.SetDefault("dispatcher.instance_id", "")
+	viper.SetDefault("dispatcher.lease_duration", "5m")
+	viper.SetDefault("dispatcher.priority_weights", map[string]int{"high": 6, "normal": 3, "bulk": 1})
+	viper.SetDefault("retry.base_delay", "30s")
+	viper.SetDefault("retry.max_delay", "1h")
+	viper.SetDefault("retry.multiplier", 2.0)
+	viper.SetDefault("retry.jitter", 0.2)
+	viper.SetDefault("retry.max_attempts", 5)
+	viper.SetDefault("scheduling.max_ahead", "720h")
+	viper.SetDefault("idempotency.retention", "24h")
+	viper.SetDefault("idempotency.reservation_timeout", "30s")
+	viper.SetDefault("batch.max_items", 50000)
+	viper.SetDefault("batch.insert_size", 1000)
+	viper.SetDefault("import.report_retention", "24h")
+	viper.SetDefault("import.dedup_window", 100000)
+	viper.SetDefault("circuit_breaker.enabled", true)
+	viper.SetDefault("circuit_breaker.window_size", 20)
+	viper.SetDefault("circuit_breaker.min_requests", 10)
+	viper.SetDefault("circuit_breaker.failure_rate", 0.5)
+	viper.SetDefault("circuit_breaker.open_duration", "30s")
+	viper.SetDefault("circuit_breaker.half_open_probes", 2)
+	viper.SetDefault("rate_limit.enabled", false)
+	viper.SetDefault("rate_limit.provider.limit", 0)
+	viper.SetDefault("rate_limit.provider.period", "1s")
+	viper.SetDefault("rate_limit.provider.burst", 0)
+	viper.SetDefault("rate_limit.recipient.limit", 3)
+	viper.SetDefault("rate_limit.recipient.period", "1m")
+	viper.SetDefault("rate_limit.recipient.burst", 0)
+	viper.SetDefault("callback.secret", "")
+	viper.SetDefault("callback.secret_file", "")
+	viper.SetDefault("callback.max_skew", "5m")
+
+	if err := viper.Unmarshal(&AppSettings); err != nil {
+		return err
+	}
+
+	// Leases held under this ID are released on startup, so two processes
+	// must never share it. Processes on one host, or pods reusing a name, get
+	// a random suffix; a stable ID has to be configured for each process.
+	if AppSettings.Dispatcher.InstanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "instance"
+		}
+		AppSettings.Dispatcher.InstanceID = fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8])
+	}
+
+	if err := loadSecrets(&AppSettings); err != nil {
+		return err
+	}
+
+	if err := validateDispatcher(&AppSettings); err != nil {
+		return err
+	}
+	if err := validateRetry(&AppSettings); err != nil {
+		return err
+	}
+
+	if AppSettings.Idempotency.ReservationTimeout <= 0 || AppSettings.Idempotency.ReservationTimeout > AppSettings.Idempotency.Retention {
+		return fmt.Errorf("idempotency.reservation_timeout must be positive and at most idempotency.retention")
+	}
+
+	if AppSettings.Import.DedupWindow <= 0 {
+		return fmt.Errorf("import.dedup_window must be positive")
+	}
+
+	// The skew bounds how long a captured callback can be replayed.
+	if AppSettings.Callback.MaxSkew <= 0 {
+		return fmt.Errorf("callback.max_skew must be positive")
+	}
+
+	normalizeWebhookProviders(&AppSettings)
+	if err := validateWebhookProviders(&AppSettings); err != nil {
+		return err
+	}
+	if err := validateRateLimits(&AppSettings); err != nil {
+		return err
+	}
+
+	return validateChannels(&AppSettings)
+}
+
+// EnabledChannels returns the delivery channels turned on in the
+// configuration.
+func (c *Configuration) EnabledChannels() []string {
+	var channels []string
+	if c.Webhook.Enabled {
+		channels = append(channels, entity.ChannelWebhook)
+	}
+	if c.File.Enabled {
+		channels = append(channels, entity.ChannelFile)
+	}
+	if c.Email.Enabled {
+		channels = append(channels, entity.ChannelEmail)
+	}
+	return channels
+}
+
+// loadSecrets reads the secrets configured as files. A file takes precedence
+// over the inline value so that mounted secrets override stale environment.
+func loadSecrets(settings *Configuration) error {
+	auth := &settings.Webhook.Auth
+	secrets := []struct {
+		key   string
+		file  string
+		value *string
+	}{
+		{"webhook.auth.header_value_file", auth.HeaderValueFile, &auth.HeaderValue},
+		{"webhook.auth.token_file", auth.TokenFile, &auth.Token},
+		{"webhook.auth.password_file", auth.PasswordFile, &auth.Password},
+		{"webhook.auth.client_secret_file", auth.ClientSecretFile, &auth.ClientSecret},
+		{"callback.secret_file", settings.Callback.SecretFile, &settings.Callback.Secret},
+		{"email.password_file", settings.Email.PasswordFile, &settings.Email.Password},
+	}
+
+	for i := range settings.Webhook.Providers {
+		auth := &settings.Webhook.Providers[i].Auth
+		key := fmt.Sprintf("webhook.providers[%d].auth", i)
+		secrets = append(secrets, []struct {
+			key   string
+			file  string
+			value *string
+		}{
+			{key + ".header_value_file", auth.HeaderValueFile, &auth.HeaderValue},
+			{key + ".token_file", auth.TokenFile, &auth.Token},
+			{key + ".password_file", auth.PasswordFile, &auth.Password},
+			{key + ".client_secret_file", auth.ClientSecretFile, &auth.ClientSecret},
+		}...)
+	}
+
+	for _, secret := range secrets {
+		if secret.file == "" {
+			continue
+		}
+		content, err := os.ReadFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", secret.key, err)
+		}
+		*secret.value = strings.TrimRight(string(content), "\r\n")
+	}
+
+	if file := settings.Webhook.Signing.SecretsFile; file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read webhook.signing.secrets_file: %w", err)
+		}
+		settings.Webhook.Signing.Secrets = strings.Fields(string(content))
+	}
+
+	if auth.Type == "" && settings.Webhook.AuthKey != "" {
+		auth.Type = WebhookAuthHeader
+		auth.HeaderName = settings.Webhook.AuthKeyName
+		auth.HeaderValue = settings.Webhook.AuthKey
+	}
+
+	return nil
+}
+
+// normalizeWebhookProviders turns the legacy single webhook into the default
+// provider and, without routes, routes every number to the providers in the
+// order they are listed.
+func normalizeWebhookProviders(settings *Configuration) {
+	webhook := &settings.Webhook
+	if len(webhook.Providers) == 0 && webhook.URL != "" {
+		webhook.Providers = []WebhookProvider{{
+			Name: DefaultWebhookProvider,
+			URL:  webhook.URL,
+			Auth: webhook.Auth,
+		}}
+	}
+
+	if len(webhook.Routes) == 0 && len(webhook.Providers) > 0 {
+		names := make([]string, len(webhook.Providers))
+		for i, provider := range webhook.Providers {
+			names[i] = provider.Name
+		}
+		webhook.Routes = []WebhookRoute{{Prefix: "+", Providers: names}}
+	}
+}
+
+func validateWebhookProviders(settings *Configuration) error {
+	webhook := &settings.Webhook
+	if !webhook.Enabled {
+		return nil
+	}
+	if len(webhook.Providers) == 0 {
+		return fmt.Errorf("webhook.url or webhook.providers is required when the webhook channel is enabled")
+	}
+
+	names := make(map[string]bool, len(webhook.Providers))
+	for i := range webhook.Providers {
+		provider := &webhook.Providers[i]
+		key := fmt.Sprintf("webhook.providers[%d]", i)
+		if provider.Name == "" || names[provider.Name] {
+			return fmt.Errorf("%s.name must be set and unique", key)
+		}
+		names[provider.Name] = true
+		if provider.URL == "" {
+			return fmt.Errorf("%s.url is required", key)
+		}
+		if provider.ContentType == ContentTypeForm && provider.Template == "" {
+			return fmt.Errorf("%s.template is required for form content", key)
+		}
+		for _, status := range provider.SuccessStatus {
+			if status < 200 || status > 299 {
+				return fmt.Errorf("%s.success_status must only list 2xx status codes", key)
+			}
+		}
+		if err := validateWebhookAuth(key+".auth", &provider.Auth); err != nil {
+			return err
+		}
+	}
+
+	prefixes := make(map[string]bool, len(webhook.Routes))
+	for i, route := range webhook.Routes {
+		key := fmt.Sprintf("webhook.routes[%d]", i)
+		if !strings.HasPrefix(route.Prefix, "+") || strings.Trim(route.Prefix[1:], "0123456789") != "" {
+			return fmt.Errorf("%s.prefix must be '+' followed by digits", key)
+		}
+		if prefixes[route.Prefix] {
+			return fmt.Errorf("%s.prefix %s is routed twice", key, route.Prefix)
+		}
+		prefixes[route.Prefix] = true
+		if len(route.Providers) == 0 {
+			return fmt.Errorf("%s.providers must list at least one provider", key)
+		}
+		for _, name := range route.Providers {
+			if !names[name] {
+				return fmt.Errorf("%s references unknown provider %q", key, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateWebhookAuth(key string, auth *WebhookAuth) error {
+	switch auth.Type {
+	case "", WebhookAuthNone:
+		auth.Type = WebhookAuthNone
+	case WebhookAuthHeader:
+		if auth.HeaderName == "" || auth.HeaderValue == "" {
+			return fmt.Errorf("%s.header_name and a header value are required for header auth", key)
+		}
+	case WebhookAuthBearer:
+		if auth.Token == "" {
+			return fmt.Errorf("a %s token is required for bearer auth", key)
+		}
+	case WebhookAuthBasic:
+		if auth.Username == "" {
+			return fmt.Errorf("%s.username is required for basic auth", key)
+		}
+	case WebhookAuthOAuth2:
+		if auth.TokenURL == "" || auth.ClientID == "" || auth.ClientSecret == "" {
+			return fmt.Errorf("%s.token_url, client_id and a client secret are required for oauth2 auth", key)
+		}
+	default:
+		return fmt.Errorf("%s.type must be one of: %s, %s, %s, %s, %s", key, WebhookAuthNone,
+			WebhookAuthHeader, WebhookAuthBearer, WebhookAuthBasic, WebhookAuthOAuth2)
+	}
+
+	return nil
+}
+
+// validateDispatcher applies the bounds of the dispatcher API to the
+// configured values, so the service does not start with settings it would
+// reject at runtime.
+func validateDispatcher(settings *Configuration) error {
+	dispatcher := settings.Dispatcher
+	if err := validator.ValidateDispatcherInterval(dispatcher.Interval.String()); err != nil {
+		return fmt.Errorf("dispatcher.interval: %w", err)
+	}
+	if err := validator.ValidateDispatcherBatchSize(dispatcher.BatchSize); err != nil {
+		return fmt.Errorf("dispatcher.batch_size: %w", err)
+	}
+	if err := validator.ValidateDispatcherMaxPerTick(dispatcher.MaxPerTick); err != nil {
+		return fmt.Errorf("dispatcher.max_per_tick: %w", err)
+	}
+	if err := validator.ValidateDispatcherWorkers(dispatcher.Workers); err != nil {
+		return fmt.Errorf("dispatcher.workers: %w", err)
+	}
+	if dispatcher.LeaseDuration <= 0 {
+		return fmt.Errorf("dispatcher.lease_duration must be positive")
+	}
+	for priority := range dispatcher.PriorityWeights {
+		if !slices.Contains(entity.Priorities, priority) {
+			return fmt.Errorf("dispatcher.priority_weights: unknown priority %q, must be one of %v", priority, entity.Priorities)
+		}
+	}
+	// A lane without a weight would never be given capacity, so messages of
+	// that priority would wait forever.
+	for _, priority := range entity.Priorities {
+		if dispatcher.PriorityWeights[priority] <= 0 {
+			return fmt.Errorf("dispatcher.priority_weights.%s must be positive", priority)
+		}
+	}
+	return nil
+}
+
+func validateRetry(settings *Configuration) error {
+	retry := settings.Retry
+	if retry.MaxAttempts <= 0 {
+		return fmt.Errorf("retry.max_attempts must be positive")
+	}
+	if retry.BaseDelay <= 0 {
+		return fmt.Errorf("retry.base_delay must be positive")
+	}
+	if retry.Multiplier < 1 {
+		return fmt.Errorf("retry.multiplier must be at least 1")
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		return fmt.Errorf("retry.jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+func validateRateLimits(settings *Configuration) error {
+	if err := validateRateLimit("rate_limit.provider", &settings.RateLimit.Provider); err != nil {
+		return err
+	}
+	if err := validateRateLimit("rate_limit.recipient", &settings.RateLimit.Recipient); err != nil {
+		return err
+	}
+	for i := range settings.Webhook.Providers {
+		key := fmt.Sprintf("webhook.providers[%d].rate_limit", i)
+		if err := validateRateLimit(key, &settings.Webhook.Providers[i].RateLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRateLimit(key string, limit *RateLimit) error {
+	if limit.Limit < 0 || limit.Burst < 0 {
+		return fmt.Errorf("%s.limit and %s.burst must not be negative", key, key)
+	}
+	if limit.Limit == 0 {
+		return nil
+	}
+	if limit.Period < time.Millisecond {
+		return fmt.Errorf("%s.period must be at least 1ms", key)
+	}
+	if limit.Burst == 0 {
+		limit.Burst = limit.Limit
+	}
+	return nil
+}
+
+func validateChannels(settings *Configuration) error {
+	enabled := settings.EnabledChannels()
+	if len(enabled) == 0 {
+		return fmt.Errorf("at least one delivery channel must be enabled")
+	}
+	if !slices.Contains(enabled, settings.Channels.Default) {
+		return fmt.Errorf("channels.default must be one of the enabled channels: %s", strings.Join(enabled, ", "))
+	}
+	if settings.File.Enabled && settings.File.Path == "" {
+		return fmt.Errorf("file.path is required when the file channel is enabled")
+	}
+	if settings.Email.Enabled {
+		if settings.Email.Host == "" || settings.Email.From == "" {
+			return fmt.Errorf("email.host and email.from are required when the email channel is enabled")
+		}
+		switch settings.Email.StartTLS {
+		case StartTLSRequired, StartTLSOpportunistic, StartTLSDisabled:
+		default:
+			return fmt.Errorf("email.starttls must be one of: %s, %s, %s", StartTLSRequired,
+				StartTLSOpportunistic, StartTLSDisabled)
+		}
+	}
 
-	return viper.Unmarshal(&AppSettings)
+	return nil
 }
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateRetry(t *testing.T) {
+	valid := func(settings *Configuration) {
+		settings.Retry.MaxAttempts = 5
+		settings.Retry.BaseDelay = 30 * time.Second
+		settings.Retry.Multiplier = 2
+		settings.Retry.Jitter = 0.2
+	}
+
+	tests := []struct {
+		name    string
+		change  func(settings *Configuration)
+		wantErr bool
+	}{
+		{"valid", func(settings *Configuration) {}, false},
+		{"constant delay", func(settings *Configuration) { settings.Retry.Multiplier = 1 }, false},
+		{"no jitter", func(settings *Configuration) { settings.Retry.Jitter = 0 }, false},
+		{"zero max attempts", func(settings *Configuration) { settings.Retry.MaxAttempts = 0 }, true},
+		{"zero base delay", func(settings *Configuration) { settings.Retry.BaseDelay = 0 }, true},
+		{"negative base delay", func(settings *Configuration) { settings.Retry.BaseDelay = -time.Second }, true},
+		{"shrinking delay", func(settings *Configuration) { settings.Retry.Multiplier = 0.5 }, true},
+		{"negative jitter", func(settings *Configuration) { settings.Retry.Jitter = -0.1 }, true},
+		{"jitter above one", func(settings *Configuration) { settings.Retry.Jitter = 1.5 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings Configuration
+			valid(&settings)
+			tt.change(&settings)
+
+			err := validateRetry(&settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDispatcherPriorityWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		wantErr bool
+	}{
+		{"every lane", map[string]int{"high": 6, "normal": 3, "bulk": 1}, false},
+		{"missing lane", map[string]int{"high": 6, "normal": 3}, true},
+		{"zero weight", map[string]int{"high": 6, "normal": 3, "bulk": 0}, true},
+		{"negative weight", map[string]int{"high": 6, "normal": -3, "bulk": 1}, true},
+		{"unknown lane", map[string]int{"high": 6, "normal": 3, "bulk": 1, "urgent": 9}, true},
+		{"no weights", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var settings Configuration
+			settings.Dispatcher.Interval = 5 * time.Second
+			settings.Dispatcher.BatchSize = 2
+			settings.Dispatcher.MaxPerTick = 2
+			settings.Dispatcher.Workers = 4
+			settings.Dispatcher.LeaseDuration = 5 * time.Minute
+			settings.Dispatcher.PriorityWeights = tt.weights
+
+			err := validateDispatcher(&settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateDispatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
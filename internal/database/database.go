@@ -88,7 +88,7 @@ func Setup() (*gorm.DB, error) {
 }
 
 func runMigrations(db *gorm.DB) error {
-	return db.AutoMigrate(&entity.Message{})
+	return db.AutoMigrate(&entity.Message{}, &entity.MessageEvent{})
 }
 
 func seedTestData(db *gorm.DB) error {
@@ -8,13 +8,31 @@ import (
 )
 
 type Message struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primarykey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	To        string         `gorm:"not null" json:"to"`
-	Content   string         `gorm:"not null;type:varchar(160)" json:"content"`
-	Status    string         `gorm:"not null;default:'pending'" json:"status"`
-	MessageID string         `gorm:"index" json:"message_id,omitempty"`
-	SentAt    time.Time      `json:"sent_at,omitempty"`
+	ID            uuid.UUID      `gorm:"type:uuid;primarykey" json:"id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	To            string         `gorm:"not null" json:"to"`
+	Content       string         `gorm:"not null;type:text" json:"content"`
+	Status        string         `gorm:"not null;default:'pending'" json:"status"`
+	MessageID     string         `gorm:"index" json:"message_id,omitempty"`
+	SentAt        time.Time      `json:"sent_at,omitempty"`
+	ClaimedBy     string         `gorm:"index" json:"claimed_by,omitempty"`
+	ClaimedUntil  *time.Time     `gorm:"index" json:"claimed_until,omitempty"`
+	AttemptCount  int            `gorm:"not null;default:0" json:"attempt_count"`
+	NextAttemptAt *time.Time     `gorm:"index" json:"next_attempt_at,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	SendAt        *time.Time     `gorm:"index" json:"send_at,omitempty"`
+	Priority      string         `gorm:"not null;default:'normal';index" json:"priority"`
+	Channel       string         `gorm:"not null;default:'webhook';index" json:"channel"`
+	// Provider is the provider that accepted the message, for example the
+	// webhook provider picked by routing and failover.
+	Provider string `gorm:"index" json:"provider,omitempty"`
+	// Subject and HTML are only used by the email channel.
+	Subject string `json:"subject,omitempty"`
+	HTML    string `gorm:"type:text" json:"html,omitempty"`
+	// DeliveryReportedAt is when the provider reported the handset delivery
+	// outcome; DeliveryError holds its reason for undelivered messages.
+	DeliveryReportedAt *time.Time `json:"delivery_reported_at,omitempty"`
+	DeliveryError      string     `json:"delivery_error,omitempty"`
 }
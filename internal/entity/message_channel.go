@@ -0,0 +1,9 @@
+package entity
+
+const (
+	ChannelWebhook = "webhook"
+	ChannelFile    = "file"
+	ChannelEmail   = "email"
+)
+
+var Channels = []string{ChannelWebhook, ChannelFile, ChannelEmail}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventCreated        = "created"
+	EventUpdated        = "updated"
+	EventCancelled      = "cancelled"
+	EventClaimed        = "claimed"
+	EventReleased       = "released"
+	EventDeferred       = "deferred"
+	EventSent           = "sent"
+	EventRetryScheduled = "retry_scheduled"
+	EventFailed         = "failed"
+	EventDelivered      = "delivered"
+	EventUndelivered    = "undelivered"
+)
+
+// MessageEvent is an append-only record of a state change of a message.
+// Status and AttemptCount are the message's values after the change.
+type MessageEvent struct {
+	ID           uint64    `gorm:"primarykey" json:"id"`
+	MessageID    uuid.UUID `gorm:"type:uuid;not null;index:idx_message_events_message_created,priority:1" json:"message_id"`
+	Type         string    `gorm:"not null" json:"type"`
+	Status       string    `gorm:"not null" json:"status"`
+	AttemptCount int       `gorm:"not null;default:0" json:"attempt_count"`
+	Detail       string    `json:"detail,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `gorm:"index:idx_message_events_message_created,priority:2" json:"created_at"`
+}
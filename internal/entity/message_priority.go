@@ -0,0 +1,10 @@
+package entity
+
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityBulk   = "bulk"
+)
+
+// Priorities lists the priority lanes from the most to the least urgent.
+var Priorities = []string{PriorityHigh, PriorityNormal, PriorityBulk}
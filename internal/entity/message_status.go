@@ -1,7 +1,16 @@
 package entity
 
 const (
-	StatusPending = "pending"
-	StatusSent    = "sent"
-	StatusFailed  = "failed"
+	StatusPending     = "pending"
+	StatusSent        = "sent"
+	StatusFailed      = "failed"
+	StatusCancelled   = "cancelled"
+	StatusDelivered   = "delivered"
+	StatusUndelivered = "undelivered"
 )
+
+var Statuses = []string{StatusPending, StatusSent, StatusFailed, StatusCancelled, StatusDelivered, StatusUndelivered}
+
+// HandedOffStatuses are the statuses of messages accepted by the provider,
+// whether or not a delivery receipt has arrived for them yet.
+var HandedOffStatuses = []string{StatusSent, StatusDelivered, StatusUndelivered}
@@ -0,0 +1,247 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auto-message-sender/internal/entity"
+)
+
+const (
+	FormatCSV      = "csv"
+	FormatNDJSON   = "ndjson"
+	FormatColumnar = "columnar"
+
+	// flushEvery is how many rows are buffered before they are pushed to the
+	// client, and the row group size of the columnar format.
+	flushEvery = 1000
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON, FormatColumnar}
+
+// Columns is the field order of every export format.
+var Columns = []string{
+	"id", "to", "content", "subject", "status", "priority", "channel", "provider", "message_id",
+	"attempt_count", "last_error", "created_at", "send_at", "sent_at",
+	"delivery_reported_at", "delivery_error",
+}
+
+// Writer encodes messages one at a time. Output is buffered, so Close must be
+// called to write the remaining rows.
+type Writer interface {
+	Write(message *entity.Message) error
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	out := &flushWriter{buf: bufio.NewWriter(w), target: w}
+
+	switch format {
+	case FormatCSV:
+		return &csvWriter{out: out, writer: csv.NewWriter(out.buf)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{out: out, encoder: json.NewEncoder(out.buf)}, nil
+	case FormatColumnar:
+		return &columnarWriter{out: out, encoder: json.NewEncoder(out.buf)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func FileExtension(format string) string {
+	if format == FormatCSV {
+		return "csv"
+	}
+	return "ndjson"
+}
+
+// flushWriter pushes buffered output through to the client every flushEvery
+// rows so long exports arrive progressively instead of at the end.
+type flushWriter struct {
+	buf    *bufio.Writer
+	target io.Writer
+	rows   int
+}
+
+// row counts a written row and reports whether the output is due a flush.
+func (w *flushWriter) row() bool {
+	w.rows++
+	return w.rows%flushEvery == 0
+}
+
+func (w *flushWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := w.target.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func record(message *entity.Message) []string {
+	return []string{
+		message.ID.String(),
+		message.To,
+		message.Content,
+		message.Subject,
+		message.Status,
+		message.Priority,
+		message.Channel,
+		message.Provider,
+		message.MessageID,
+		strconv.Itoa(message.AttemptCount),
+		message.LastError,
+		formatTime(message.CreatedAt),
+		formatOptionalTime(message.SendAt),
+		formatTime(message.SentAt),
+		formatOptionalTime(message.DeliveryReportedAt),
+		message.DeliveryError,
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+type csvWriter struct {
+	out           *flushWriter
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.writer.Write(Columns)
+}
+
+func (w *csvWriter) Write(message *entity.Message) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	if err := w.writer.Write(record(message)); err != nil {
+		return err
+	}
+	if !w.out.row() {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *csvWriter) flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.out.flush()
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+type ndjsonWriter struct {
+	out     *flushWriter
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(message *entity.Message) error {
+	values := record(message)
+	line := make(map[string]string, len(Columns))
+	for i, column := range Columns {
+		line[column] = values[i]
+	}
+
+	if err := w.encoder.Encode(line); err != nil {
+		return err
+	}
+	if !w.out.row() {
+		return nil
+	}
+	return w.out.flush()
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.out.flush()
+}
+
+// columnarWriter writes row groups of up to flushEvery messages. Each line is
+// a JSON object holding one array per column, which compresses well and can
+// be loaded column by column into analytical tools.
+type columnarWriter struct {
+	out     *flushWriter
+	encoder *json.Encoder
+	group   [][]string
+}
+
+type rowGroup struct {
+	Rows    int                 `json:"rows"`
+	Columns map[string][]string `json:"columns"`
+}
+
+func (w *columnarWriter) Write(message *entity.Message) error {
+	w.group = append(w.group, record(message))
+	if len(w.group) < flushEvery {
+		return nil
+	}
+	if err := w.writeGroup(); err != nil {
+		return err
+	}
+	return w.out.flush()
+}
+
+func (w *columnarWriter) writeGroup() error {
+	if len(w.group) == 0 {
+		return nil
+	}
+
+	group := rowGroup{Rows: len(w.group), Columns: make(map[string][]string, len(Columns))}
+	for i, column := range Columns {
+		values := make([]string, len(w.group))
+		for j, row := range w.group {
+			values[j] = row[i]
+		}
+		group.Columns[column] = values
+	}
+
+	w.group = w.group[:0]
+	return w.encoder.Encode(group)
+}
+
+func (w *columnarWriter) Close() error {
+	if err := w.writeGroup(); err != nil {
+		return err
+	}
+	return w.out.flush()
+}
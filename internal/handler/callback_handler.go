@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/model/response"
+	"auto-message-sender/internal/service"
+	"auto-message-sender/pkg/logger"
+	"auto-message-sender/pkg/signature"
+)
+
+const maxCallbackBodySize = 64 << 10
+
+type CallbackHandler interface {
+	DeliveryReceipt(c echo.Context) error
+	RegisterRoutes(group *echo.Group)
+}
+
+type callbackHandler struct {
+	svc      service.MessageService
+	verifier *signature.Verifier
+}
+
+func NewCallbackHandler(svc service.MessageService) CallbackHandler {
+	settings := config.AppSettings.Callback
+	if settings.Secret == "" {
+		logger.Warn("Callback secret is not configured, delivery receipts will be rejected")
+	}
+
+	return &callbackHandler{
+		svc:      svc,
+		verifier: signature.NewVerifier(settings.MaxSkew, settings.Secret),
+	}
+}
+
+func (h *callbackHandler) RegisterRoutes(group *echo.Group) {
+	group.POST("/delivery", h.DeliveryReceipt)
+	group.POST("/delivery/:provider", h.DeliveryReceipt)
+}
+
+// DeliveryReceipt @Summary Receive a delivery receipt
+// @Description Called by the provider with the handset delivery outcome of a sent message. The request must carry X-Signature-Timestamp (unix seconds) and X-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body)). Providers configured under webhook.providers post to /callbacks/delivery/{provider}; /callbacks/delivery is for the default provider.
+// @Tags callbacks
+// @Accept json
+// @Produce json
+// @Param X-Signature header string true "sha256= followed by the hex HMAC-SHA256 of timestamp.body"
+// @Param X-Signature-Timestamp header string true "Unix time the request was signed at"
+// @Param provider path string false "Name of the webhook provider sending the receipt"
+// @Param receipt body request.DeliveryReceiptRequest true "Delivery receipt"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /callbacks/delivery [post]
+// @Router /callbacks/delivery/{provider} [post]
+func (h *callbackHandler) DeliveryReceipt(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxCallbackBodySize+1))
+	if err != nil || len(body) > maxCallbackBodySize {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request body",
+		})
+	}
+
+	err = h.verifier.Verify(
+		c.Request().Header.Get(signature.HeaderTimestamp),
+		c.Request().Header.Get(signature.HeaderSignature),
+		body,
+	)
+	if err != nil {
+		logger.WithField("remoteIP", c.RealIP()).WithError(err).Warn("Rejected delivery receipt with invalid signature")
+		return c.JSON(http.StatusUnauthorized, response.ErrorResponse{
+			Error: "Invalid signature",
+		})
+	}
+
+	req := new(request.DeliveryReceiptRequest)
+	if err := json.Unmarshal(body, req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	req.Provider = c.Param("provider")
+	if req.Provider == "" {
+		req.Provider = config.DefaultWebhookProvider
+	}
+	if !knownProvider(req.Provider) {
+		return c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Error: "Unknown webhook provider",
+		})
+	}
+
+	if _, err := h.svc.RecordDeliveryReceipt(c.Request().Context(), req); err != nil {
+		switch {
+		case errors.Is(err, service.ErrMessageNotFound):
+			return c.JSON(http.StatusNotFound, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		case errors.Is(err, service.ErrMessageNotSent):
+			return c.JSON(http.StatusConflict, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		default:
+			return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, response.SuccessResponse{
+		Message: "Delivery receipt recorded",
+	})
+}
+
+func knownProvider(name string) bool {
+	for _, provider := range config.AppSettings.Webhook.Providers {
+		if provider.Name == name {
+			return true
+		}
+	}
+	return false
+}
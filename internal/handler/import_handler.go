@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"auto-message-sender/internal/importer"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/model/response"
+	"auto-message-sender/internal/service"
+)
+
+const importErrorsRouteName = "import-errors"
+
+type ImportHandler interface {
+	ImportMessages(c echo.Context) error
+	GetImport(c echo.Context) error
+	GetImportErrors(c echo.Context) error
+	RegisterRoutes(group *echo.Group)
+}
+
+type importHandler struct {
+	svc service.ImportService
+}
+
+func NewImportHandler(svc service.ImportService) ImportHandler {
+	return &importHandler{svc: svc}
+}
+
+func (h *importHandler) RegisterRoutes(group *echo.Group) {
+	group.POST("/import", h.ImportMessages)
+	group.GET("/import/:id", h.GetImport)
+	group.GET("/import/:id/errors", h.GetImportErrors).Name = importErrorsRouteName
+}
+
+// ImportMessages @Summary Import messages from a CSV or NDJSON file
+// @Description Stream a CSV (with a header row) or NDJSON file, either as the raw request body or as the "file" field of a multipart form, and queue every valid row as a pending message
+// @Tags messages
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Produce json
+// @Param format query string false "File format (csv/ndjson), detected from the content type or file name when omitted"
+// @Param to_column query string false "Column holding the recipient" default(to)
+// @Param content_column query string false "Column holding the message content" default(content)
+// @Param subject_column query string false "Column holding the email subject"
+// @Param html_column query string false "Column holding the email HTML body"
+// @Param template query string false "Go text/template rendering the content from the row's columns, e.g. Hello {{.name}}"
+// @Param priority query string false "Priority of the imported messages (high/normal/bulk)"
+// @Param channel query string false "Delivery channel of the imported messages, the configured default when omitted"
+// @Success 201 {object} response.ImportResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ImportResponse
+// @Router /messages/import [post]
+func (h *importHandler) ImportMessages(c echo.Context) error {
+	req := &request.ImportMessagesRequest{
+		ToColumn:      "to",
+		ContentColumn: "content",
+	}
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+
+	source, detectedFormat, err := importSource(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	if req.Format == "" {
+		req.Format = detectedFormat
+	}
+
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
+
+	summary, err := h.svc.Import(c.Request().Context(), source, service.ImportOptions{
+		Format:          req.Format,
+		ToColumn:        req.ToColumn,
+		ContentColumn:   req.ContentColumn,
+		ContentTemplate: req.Template,
+		SubjectColumn:   req.SubjectColumn,
+		HTMLColumn:      req.HTMLColumn,
+		Priority:        req.Priority,
+		Channel:         req.Channel,
+	})
+	if err != nil {
+		if summary == nil {
+			return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, h.toImportResponse(c, summary))
+	}
+
+	return c.JSON(http.StatusCreated, h.toImportResponse(c, summary))
+}
+
+// GetImport @Summary Get an import summary
+// @Description Get the counts of accepted, rejected and duplicate rows of an import
+// @Tags messages
+// @Produce json
+// @Param id path string true "Import ID"
+// @Success 200 {object} response.ImportResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/import/{id} [get]
+func (h *importHandler) GetImport(c echo.Context) error {
+	summary, err := h.svc.GetSummary(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return importError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, h.toImportResponse(c, summary))
+}
+
+// GetImportErrors @Summary Download the error report of an import
+// @Description Download the rejected and duplicate rows of an import as CSV with the columns line, reason and row
+// @Tags messages
+// @Produce text/csv
+// @Param id path string true "Import ID"
+// @Success 200 {string} string "CSV error report"
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/import/{id}/errors [get]
+func (h *importHandler) GetImportErrors(c echo.Context) error {
+	importID := c.Param("id")
+	if _, err := h.svc.GetSummary(c.Request().Context(), importID); err != nil {
+		return importError(c, err)
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "import-"+importID+"-errors.csv"))
+	res.WriteHeader(http.StatusOK)
+
+	return h.svc.WriteErrorReport(c.Request().Context(), importID, res)
+}
+
+func (h *importHandler) toImportResponse(c echo.Context, summary *service.ImportSummary) response.ImportResponse {
+	return response.ImportResponse{
+		ImportID:       summary.ImportID,
+		Format:         summary.Format,
+		Status:         summary.Status,
+		Error:          summary.Error,
+		Total:          summary.Total,
+		Accepted:       summary.Accepted,
+		Rejected:       summary.Rejected,
+		Duplicates:     summary.Duplicates,
+		StartedAt:      summary.StartedAt.Format(time.RFC3339),
+		FinishedAt:     summary.FinishedAt.Format(time.RFC3339),
+		ErrorReportURL: c.Echo().Reverse(importErrorsRouteName, summary.ImportID),
+	}
+}
+
+func importError(c echo.Context, err error) error {
+	if errors.Is(err, service.ErrImportNotFound) {
+		return c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+		Error: err.Error(),
+	})
+}
+
+// importSource returns a reader over the uploaded file without buffering it.
+// Multipart forms are read part by part up to the "file" field.
+func importSource(req *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+
+	if mediaType != echo.MIMEMultipartForm {
+		return req.Body, formatFromMediaType(mediaType), nil
+	}
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid multipart form: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.New("multipart form has no \"file\" field")
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid multipart form: %w", err)
+		}
+
+		if part.FormName() == "file" {
+			format := formatFromFileName(part.FileName())
+			if format == "" {
+				format = formatFromMediaType(part.Header.Get(echo.HeaderContentType))
+			}
+			return part, format, nil
+		}
+	}
+}
+
+func formatFromMediaType(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "text/csv", "application/csv":
+		return importer.FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return importer.FormatNDJSON
+	default:
+		return ""
+	}
+}
+
+func formatFromFileName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return importer.FormatCSV
+	case ".ndjson", ".jsonl":
+		return importer.FormatNDJSON
+	default:
+		return ""
+	}
+}
@@ -2,25 +2,47 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
+	"auto-message-sender/internal/exporter"
 	"auto-message-sender/internal/model/request"
 	"auto-message-sender/internal/model/response"
 	"auto-message-sender/internal/service"
+	"auto-message-sender/internal/validator"
+	"auto-message-sender/pkg/logger"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 )
 
 type MessageHandler interface {
 	StartSending(c echo.Context) error
 	StopSending(c echo.Context) error
 	GetMessages(c echo.Context) error
+	ExportMessages(c echo.Context) error
+	GetMessage(c echo.Context) error
+	GetMessageEvents(c echo.Context) error
+	UpdateMessage(c echo.Context) error
+	CancelMessage(c echo.Context) error
 	CreateMessage(c echo.Context) error
+	CreateMessages(c echo.Context) error
+	GetDispatcher(c echo.Context) error
+	UpdateDispatcher(c echo.Context) error
 	RegisterRoutes(group *echo.Group)
 }
 
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	batchItemAccepted = "accepted"
+	batchItemRejected = "rejected"
+)
+
 type messageHandler struct {
 	svc service.MessageService
 }
@@ -33,7 +55,15 @@ func (h *messageHandler) RegisterRoutes(group *echo.Group) {
 	group.POST("/start", h.StartSending)
 	group.POST("/stop", h.StopSending)
 	group.GET("", h.GetMessages)
+	group.GET("/export", h.ExportMessages)
 	group.POST("", h.CreateMessage)
+	group.POST("/batch", h.CreateMessages)
+	group.GET("/dispatcher", h.GetDispatcher)
+	group.PATCH("/dispatcher", h.UpdateDispatcher)
+	group.GET("/:id", h.GetMessage)
+	group.GET("/:id/events", h.GetMessageEvents)
+	group.PATCH("/:id", h.UpdateMessage)
+	group.DELETE("/:id", h.CancelMessage)
 }
 
 // StartSending @Summary Start automatic message sending
@@ -77,15 +107,26 @@ func (h *messageHandler) StopSending(c echo.Context) error {
 }
 
 // GetMessages @Summary Get messages
-// @Description Get a list of messages with optional filtering
+// @Description Get a list of messages with optional filtering. Without a status filter sent, delivered and undelivered messages are returned.
 // @Tags messages
 // @Accept json
 // @Produce json
-// @Param status query string false "Message status (pending/sent/failed)"
-// @Param start_date query string false "Start date (YYYY-MM-DD)"
-// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param status query []string false "Message statuses (pending/sent/failed/cancelled/delivered/undelivered), repeated or comma separated; 'all' for every status" collectionFormat(multi) default(sent)
+// @Param to query string false "Recipient phone number or email address (exact match)"
+// @Param to_prefix query string false "Recipient phone number prefix, e.g. +90532"
+// @Param content query string false "Case-insensitive content substring"
+// @Param message_id query string false "Webhook message ID"
+// @Param channel query string false "Delivery channel (webhook/file/email)"
+// @Param provider query string false "Provider that accepted the message"
+// @Param start_date query string false "Sent at start date (YYYY-MM-DD)"
+// @Param end_date query string false "Sent at end date (YYYY-MM-DD)"
+// @Param created_from query string false "Created at lower bound (YYYY-MM-DD or RFC3339)"
+// @Param created_to query string false "Created at upper bound (YYYY-MM-DD or RFC3339, dates are inclusive)"
+// @Param sort query string false "Sort field (sent_at/created_at/send_at/to/status/priority), prefix with '-' for descending" default(-sent_at)
 // @Param page query int false "Page number" default(1) minimum(1)
 // @Param page_size query int false "Page size" default(10) minimum(1) maximum(100)
+// @Param pagination query string false "Pagination mode (offset/cursor)" default(offset)
+// @Param cursor query string false "Opaque next_cursor from the previous page; implies cursor pagination"
 // @Success 200 {object} response.MessageListResponse
 // @Failure 400 {object} response.ValidationErrorResponse
 // @Failure 500 {object} response.ErrorResponse
@@ -111,47 +152,300 @@ func (h *messageHandler) GetMessages(c echo.Context) error {
 		})
 	}
 
-	messages, err := h.svc.GetMessages(filter)
+	page, err := h.svc.GetMessages(filter)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
 
-	messageItems := make([]response.MessageItem, len(messages))
-	for i, msg := range messages {
+	messageItems := make([]response.MessageItem, len(page.Messages))
+	for i, msg := range page.Messages {
 		messageItems[i] = response.MessageItem{
 			ID:        msg.ID.String(),
 			To:        msg.To,
 			Content:   msg.Content,
+			Subject:   msg.Subject,
 			Status:    msg.Status,
 			MessageID: msg.MessageID,
-			SentAt:    msg.SentAt.Format(time.RFC3339),
+			Provider:  msg.Provider,
+			SentAt:    formatOptionalTime(&msg.SentAt),
+			SendAt:    formatOptionalTime(msg.SendAt),
+			Priority:  msg.Priority,
+			Channel:   msg.Channel,
 		}
 	}
 
-	totalPages := 0
-	if len(messageItems) > 0 {
-		totalPages = (len(messageItems) + filter.PageSize - 1) / filter.PageSize
+	if filter.CursorMode() {
+		return c.JSON(http.StatusOK, response.MessageListResponse{
+			Messages:   messageItems,
+			PageSize:   filter.PageSize,
+			NextCursor: page.NextCursor,
+		})
 	}
 
+	totalPages := int((page.Total + int64(filter.PageSize) - 1) / int64(filter.PageSize))
+
 	return c.JSON(http.StatusOK, response.MessageListResponse{
 		Messages:   messageItems,
-		Total:      len(messageItems),
-		Page:       filter.Page,
+		Total:      &page.Total,
+		Page:       &filter.Page,
 		PageSize:   filter.PageSize,
-		TotalPages: totalPages,
+		TotalPages: &totalPages,
+	})
+}
+
+// ExportMessages @Summary Export messages
+// @Description Stream every message matching the filters as CSV, NDJSON or columnar NDJSON. The columnar format writes one JSON object per group of up to 1000 messages, holding an array per column. Without a status filter sent, delivered and undelivered messages are exported. A failure after the download has started aborts the connection, so a partial file is never mistaken for a complete one.
+// @Tags messages
+// @Produce text/csv,application/x-ndjson
+// @Param format query string false "Export format (csv/ndjson/columnar)" default(csv)
+// @Param status query []string false "Message statuses (pending/sent/failed/cancelled/delivered/undelivered), repeated or comma separated; 'all' for every status" collectionFormat(multi) default(sent)
+// @Param to query string false "Recipient phone number or email address (exact match)"
+// @Param to_prefix query string false "Recipient phone number prefix, e.g. +90532"
+// @Param content query string false "Case-insensitive content substring"
+// @Param message_id query string false "Webhook message ID"
+// @Param channel query string false "Delivery channel (webhook/file/email)"
+// @Param provider query string false "Provider that accepted the message"
+// @Param start_date query string false "Sent at start date (YYYY-MM-DD)"
+// @Param end_date query string false "Sent at end date (YYYY-MM-DD)"
+// @Param created_from query string false "Created at lower bound (YYYY-MM-DD or RFC3339)"
+// @Param created_to query string false "Created at upper bound (YYYY-MM-DD or RFC3339, dates are inclusive)"
+// @Param sort query string false "Sort field (sent_at/created_at/send_at/to/status/priority), prefix with '-' for descending" default(-sent_at)
+// @Success 200 {string} string "Exported messages"
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/export [get]
+func (h *messageHandler) ExportMessages(c echo.Context) error {
+	req := &request.ExportMessagesRequest{Format: exporter.FormatCSV}
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	fileName := fmt.Sprintf("messages-%s.%s", time.Now().UTC().Format("20060102T150405Z"), exporter.FileExtension(req.Format))
+
+	// The status is sent with the first rows, so a query that fails before
+	// then is still reported as an error.
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, exporter.ContentType(req.Format))
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fileName))
+
+	exported, err := h.svc.ExportMessages(c.Request().Context(), &req.MessageFilterRequest, req.Format, res)
+	if err == nil {
+		return nil
+	}
+	if !res.Committed {
+		res.Header().Del(echo.HeaderContentDisposition)
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	// Part of the file is already sent with a 200. Aborting the connection
+	// makes the client see a failed download instead of a short file.
+	logger.WithFields(logrus.Fields{
+		"exported": exported,
+		"error":    err.Error(),
+	}).Error("Aborting message export after the response started")
+	panic(http.ErrAbortHandler)
+}
+
+// GetMessage @Summary Get a message
+// @Description Get a single message with its full delivery lifecycle
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} response.MessageDetailResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/{id} [get]
+func (h *messageHandler) GetMessage(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid message ID",
+		})
+	}
+
+	details, err := h.svc.GetMessage(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrMessageNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, toMessageDetailResponse(details))
+}
+
+// GetMessageEvents @Summary Get the event history of a message
+// @Description List every state change of a message, oldest first: creation, edits, claims, attempts, retries, failure and delivery
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} response.MessageEventListResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/{id}/events [get]
+func (h *messageHandler) GetMessageEvents(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid message ID",
+		})
+	}
+
+	events, err := h.svc.GetMessageEvents(c.Request().Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrMessageNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	items := make([]response.MessageEventItem, len(events))
+	for i, event := range events {
+		items[i] = response.MessageEventItem{
+			Type:         event.Type,
+			Status:       event.Status,
+			AttemptCount: event.AttemptCount,
+			Detail:       event.Detail,
+			Error:        event.Error,
+			CreatedAt:    event.CreatedAt.Format(time.RFC3339Nano),
+		}
+	}
+
+	return c.JSON(http.StatusOK, response.MessageEventListResponse{
+		MessageID: id.String(),
+		Events:    items,
 	})
 }
 
+// UpdateMessage @Summary Edit a pending message
+// @Description Change the recipient, content or schedule of a message that has not been claimed for sending yet; an empty send_at sends it on the next dispatch
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Param message body request.UpdateMessageRequest true "Fields to change"
+// @Success 200 {object} response.MessageDetailResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/{id} [patch]
+func (h *messageHandler) UpdateMessage(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid message ID",
+		})
+	}
+
+	req := new(request.UpdateMessageRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
+
+	message, err := h.svc.UpdateMessage(c.Request().Context(), id, req)
+	if err != nil {
+		return pendingChangeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, toMessageDetailResponse(&service.MessageDetails{Message: *message}))
+}
+
+// CancelMessage @Summary Cancel a pending message
+// @Description Cancel a message that has not been claimed for sending yet
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} response.MessageDetailResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/{id} [delete]
+func (h *messageHandler) CancelMessage(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid message ID",
+		})
+	}
+
+	message, err := h.svc.CancelMessage(c.Request().Context(), id)
+	if err != nil {
+		return pendingChangeError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, toMessageDetailResponse(&service.MessageDetails{Message: *message}))
+}
+
+func pendingChangeError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrMessageNotFound):
+		return c.JSON(http.StatusNotFound, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, service.ErrMessageNotPending):
+		return c.JSON(http.StatusConflict, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidUpdate):
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+}
+
 // CreateMessage @Summary Create a new message
-// @Description Create a new message to be sent
+// @Description Create a new message to be sent, optionally scheduled with an RFC3339 send_at
 // @Tags messages
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Unique key that makes retries of this request safe"
 // @Param message body request.SendMessageRequest true "Message details"
 // @Success 201 {object} response.MessageResponse
 // @Failure 400 {object} response.ValidationErrorResponse
+// @Failure 409 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /messages [post]
 func (h *messageHandler) CreateMessage(c echo.Context) error {
@@ -166,16 +460,226 @@ func (h *messageHandler) CreateMessage(c echo.Context) error {
 			Error: fmt.Sprintf("Validation error: %s", err.Error()),
 		})
 	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
+
+	if idempotencyKey := c.Request().Header.Get(idempotencyKeyHeader); idempotencyKey != "" {
+		return h.createMessageIdempotent(c, idempotencyKey, req)
+	}
+
+	message, err := h.svc.CreateMessage(c.Request().Context(), req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, response.MessageResponse{
+		Message:   "Message created successfully",
+		MessageID: message.ID.String(),
+	})
+}
+
+func (h *messageHandler) createMessageIdempotent(c echo.Context, idempotencyKey string, req *request.SendMessageRequest) error {
+	if err := validator.ValidateIdempotencyKey(idempotencyKey); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
 
-	message, err := h.svc.CreateMessage(c.Request().Context(), req.To, req.Content)
+	message, replayed, err := h.svc.CreateMessageIdempotent(c.Request().Context(), idempotencyKey, req)
 	if err != nil {
+		if errors.Is(err, service.ErrIdempotencyKeyConflict) || errors.Is(err, service.ErrIdempotencyKeyInProgress) {
+			return c.JSON(http.StatusConflict, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+		if errors.Is(err, service.ErrMessageNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
 			Error: err.Error(),
 		})
 	}
 
+	if replayed {
+		c.Response().Header().Set(idempotentReplayedHeader, "true")
+	}
+
 	return c.JSON(http.StatusCreated, response.MessageResponse{
 		Message:   "Message created successfully",
 		MessageID: message.ID.String(),
 	})
 }
+
+// CreateMessages @Summary Create messages in bulk
+// @Description Validate each message of the batch and queue the valid ones; the result of every item is reported by its index
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param messages body request.SendMessageBatchRequest true "Messages to create"
+// @Success 201 {object} response.BatchMessageResponse
+// @Failure 400 {object} response.BatchMessageResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/batch [post]
+func (h *messageHandler) CreateMessages(c echo.Context) error {
+	var batch request.SendMessageBatchRequest
+	if err := c.Bind(&batch); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+	if err := batch.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
+
+	results := make([]response.BatchMessageResult, len(batch))
+	accepted := make([]*request.SendMessageRequest, 0, len(batch))
+	acceptedIndexes := make([]int, 0, len(batch))
+	for i := range batch {
+		results[i] = response.BatchMessageResult{Index: i}
+
+		item := &batch[i]
+		err := c.Validate(item)
+		if err == nil {
+			err = item.Validate()
+		}
+		if err != nil {
+			results[i].Status = batchItemRejected
+			results[i].Error = fmt.Sprintf("Validation error: %s", err.Error())
+			continue
+		}
+
+		accepted = append(accepted, item)
+		acceptedIndexes = append(acceptedIndexes, i)
+	}
+
+	if len(accepted) > 0 {
+		messages, err := h.svc.CreateMessages(c.Request().Context(), accepted)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+				Error: err.Error(),
+			})
+		}
+
+		for i, message := range messages {
+			result := &results[acceptedIndexes[i]]
+			result.Status = batchItemAccepted
+			result.MessageID = message.ID.String()
+		}
+	}
+
+	statusCode := http.StatusCreated
+	if len(accepted) == 0 {
+		statusCode = http.StatusBadRequest
+	}
+
+	return c.JSON(statusCode, response.BatchMessageResponse{
+		Accepted: len(accepted),
+		Rejected: len(batch) - len(accepted),
+		Results:  results,
+	})
+}
+
+// GetDispatcher @Summary Get dispatcher settings
+// @Description Get the current settings of the sending loop and the utilisation of its worker pool
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.DispatcherResponse
+// @Router /messages/dispatcher [get]
+func (h *messageHandler) GetDispatcher(c echo.Context) error {
+	return c.JSON(http.StatusOK, toDispatcherResponse(h.svc.GetDispatcherSettings()))
+}
+
+// UpdateDispatcher @Summary Update dispatcher settings
+// @Description Change the interval, batch size, per-tick limit or worker count of the sending loop without a restart
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param settings body request.UpdateDispatcherRequest true "Dispatcher settings"
+// @Success 200 {object} response.DispatcherResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /messages/dispatcher [patch]
+func (h *messageHandler) UpdateDispatcher(c echo.Context) error {
+	req := new(request.UpdateDispatcherRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: "Invalid request format",
+		})
+	}
+	if err := req.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResponse{
+			Error: fmt.Sprintf("Validation error: %s", err.Error()),
+		})
+	}
+
+	settings, err := h.svc.UpdateDispatcherSettings(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.ErrorResponse{
+			Error: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, toDispatcherResponse(settings))
+}
+
+func toDispatcherResponse(settings service.DispatcherSettings) response.DispatcherResponse {
+	return response.DispatcherResponse{
+		Interval:   settings.Interval.String(),
+		BatchSize:  settings.BatchSize,
+		MaxPerTick: settings.MaxPerTick,
+		Workers:    settings.Workers,
+		Running:    settings.Running,
+		Pool: response.WorkerPoolResponse{
+			Workers:     settings.Pool.Workers,
+			Busy:        settings.Pool.Busy,
+			Queued:      settings.Pool.Queued,
+			Processed:   settings.Pool.Processed,
+			Utilisation: settings.Pool.Utilisation,
+		},
+	}
+}
+
+func toMessageDetailResponse(details *service.MessageDetails) response.MessageDetailResponse {
+	msg := details.Message
+	return response.MessageDetailResponse{
+		ID:                 msg.ID.String(),
+		To:                 msg.To,
+		Content:            msg.Content,
+		Subject:            msg.Subject,
+		HTML:               msg.HTML,
+		Status:             msg.Status,
+		Priority:           msg.Priority,
+		Channel:            msg.Channel,
+		AttemptCount:       msg.AttemptCount,
+		LastError:          msg.LastError,
+		NextAttemptAt:      formatOptionalTime(msg.NextAttemptAt),
+		SendAt:             formatOptionalTime(msg.SendAt),
+		ClaimedBy:          msg.ClaimedBy,
+		ClaimedUntil:       formatOptionalTime(msg.ClaimedUntil),
+		MessageID:          msg.MessageID,
+		Provider:           msg.Provider,
+		SentAt:             formatOptionalTime(&msg.SentAt),
+		CachedSentAt:       formatOptionalTime(details.CachedSentAt),
+		DeliveryReportedAt: formatOptionalTime(msg.DeliveryReportedAt),
+		DeliveryError:      msg.DeliveryError,
+		CreatedAt:          msg.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:          msg.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
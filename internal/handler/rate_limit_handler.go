@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"auto-message-sender/internal/model/response"
+	"auto-message-sender/internal/service"
+)
+
+type RateLimitHandler interface {
+	GetRateLimits(c echo.Context) error
+	RegisterRoutes(group *echo.Group)
+}
+
+type rateLimitHandler struct {
+	limiter service.RateLimiter
+}
+
+func NewRateLimitHandler(limiter service.RateLimiter) RateLimitHandler {
+	return &rateLimitHandler{limiter: limiter}
+}
+
+func (h *rateLimitHandler) RegisterRoutes(group *echo.Group) {
+	group.GET("/rate-limits", h.GetRateLimits)
+}
+
+// GetRateLimits @Summary Get outbound rate limits
+// @Description Get the provider and recipient rate limits with the tokens left in their shared buckets. Recipient buckets are per number, so their tokens are only included for the recipient given in to. The allowed, deferred and errors counters are for the instance serving the request.
+// @Tags messages
+// @Accept json
+// @Produce json
+// @Param to query string false "Recipient whose bucket to include"
+// @Success 200 {object} response.RateLimitResponse
+// @Router /messages/rate-limits [get]
+func (h *rateLimitHandler) GetRateLimits(c echo.Context) error {
+	stats := h.limiter.Stats(c.Request().Context(), c.QueryParam("to"))
+
+	providers := make(map[string]response.RateLimitBucketResponse, len(stats.Providers))
+	for name, bucket := range stats.Providers {
+		providers[name] = toRateLimitBucketResponse(bucket)
+	}
+
+	return c.JSON(http.StatusOK, response.RateLimitResponse{
+		Enabled:   stats.Enabled,
+		Providers: providers,
+		Recipient: toRateLimitBucketResponse(stats.Recipient),
+	})
+}
+
+func toRateLimitBucketResponse(bucket service.RateLimitBucketStats) response.RateLimitBucketResponse {
+	return response.RateLimitBucketResponse{
+		Limit:    bucket.Limit.Limit,
+		Period:   bucket.Limit.Period.String(),
+		Burst:    bucket.Limit.Burst,
+		Tokens:   bucket.Tokens,
+		Allowed:  bucket.Allowed,
+		Deferred: bucket.Deferred,
+		Errors:   bucket.Errors,
+	}
+}
@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var Formats = []string{FormatCSV, FormatNDJSON}
+
+// RowError is returned by RowReader.Next for a row that could not be
+// decoded. Reading can continue with the next row.
+type RowError struct {
+	Line int
+	Raw  string
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Row is a decoded record keyed by column name.
+type Row struct {
+	Line   int
+	Fields map[string]string
+	Raw    string
+}
+
+// RowReader streams rows one at a time so that files of any size can be
+// imported without loading them into memory. Next returns io.EOF after the
+// last row.
+type RowReader interface {
+	Next() (*Row, error)
+}
+
+func NewRowReader(format string, r io.Reader) (RowReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("csv file is empty")
+		}
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	for i, column := range header {
+		columns[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+	}
+
+	return &csvReader{reader: reader, header: columns}, nil
+}
+
+func (r *csvReader) Next() (*Row, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	raw, err := encodeRecord(record)
+	if err != nil {
+		return nil, err
+	}
+	if len(record) != len(r.header) {
+		return nil, &RowError{
+			Line: line,
+			Raw:  raw,
+			Err:  fmt.Errorf("expected %d columns, got %d", len(r.header), len(record)),
+		}
+	}
+
+	fields := make(map[string]string, len(r.header))
+	for i, column := range r.header {
+		fields[column] = record[i]
+	}
+
+	return &Row{Line: line, Fields: fields, Raw: raw}, nil
+}
+
+// encodeRecord turns a record back into a CSV line, quoting fields that
+// contain commas, quotes or line breaks.
+func encodeRecord(record []string) (string, error) {
+	var buf strings.Builder
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(record); err != nil {
+		return "", err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) Next() (*Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		raw := strings.TrimSpace(r.scanner.Text())
+		if raw == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			return nil, &RowError{Line: r.line, Raw: raw, Err: fmt.Errorf("invalid JSON object: %w", err)}
+		}
+
+		fields := make(map[string]string, len(object))
+		for key, value := range object {
+			switch v := value.(type) {
+			case nil:
+				fields[key] = ""
+			case string:
+				fields[key] = v
+			default:
+				encoded, _ := json.Marshal(v)
+				fields[key] = string(encoded)
+			}
+		}
+
+		return &Row{Line: r.line, Fields: fields, Raw: raw}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVReader(t *testing.T) {
+	input := "\ufeffto, content\n" +
+		"+905551234567,Hello\n" +
+		"+905551234568,\"Hello, \"\"friend\"\"\"\n" +
+		"+905551234569,one,too many\n" +
+		"+905551234570,\"two\nlines\"\n"
+
+	reader, err := NewRowReader(FormatCSV, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewRowReader() error = %v", err)
+	}
+
+	tests := []struct {
+		wantLine   int
+		wantFields map[string]string
+		wantRaw    string
+		wantErr    bool
+	}{
+		{2, map[string]string{"to": "+905551234567", "content": "Hello"}, "+905551234567,Hello", false},
+		{3, map[string]string{"to": "+905551234568", "content": `Hello, "friend"`}, `+905551234568,"Hello, ""friend"""`, false},
+		{4, nil, "+905551234569,one,too many", true},
+		{5, map[string]string{"to": "+905551234570", "content": "two\nlines"}, "+905551234570,\"two\nlines\"", false},
+	}
+
+	for _, tt := range tests {
+		row, err := reader.Next()
+		if tt.wantErr {
+			var rowErr *RowError
+			if !errors.As(err, &rowErr) {
+				t.Fatalf("line %d: Next() error = %v, want a RowError", tt.wantLine, err)
+			}
+			if rowErr.Line != tt.wantLine || rowErr.Raw != tt.wantRaw {
+				t.Errorf("RowError = line %d raw %q, want line %d raw %q", rowErr.Line, rowErr.Raw, tt.wantLine, tt.wantRaw)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("line %d: Next() error = %v", tt.wantLine, err)
+		}
+		if row.Line != tt.wantLine || !reflect.DeepEqual(row.Fields, tt.wantFields) || row.Raw != tt.wantRaw {
+			t.Errorf("Next() = line %d %v raw %q, want line %d %v raw %q",
+				row.Line, row.Fields, row.Raw, tt.wantLine, tt.wantFields, tt.wantRaw)
+		}
+
+		// The raw row is written to the error report as is, so it must
+		// read back as the same record.
+		record, err := csv.NewReader(strings.NewReader(row.Raw)).Read()
+		if err != nil || record[1] != tt.wantFields["content"] {
+			t.Errorf("raw row %q reads back as %q, %v", row.Raw, record, err)
+		}
+	}
+
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Next() after the last row error = %v, want io.EOF", err)
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"to":"+905551234567","content":"Hello","count":3,"note":null}` + "\n" +
+		"\n" +
+		`{"to":` + "\n"
+
+	reader, err := NewRowReader(FormatNDJSON, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewRowReader() error = %v", err)
+	}
+
+	row, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	want := map[string]string{"to": "+905551234567", "content": "Hello", "count": "3", "note": ""}
+	if row.Line != 1 || !reflect.DeepEqual(row.Fields, want) {
+		t.Errorf("Next() = line %d %v, want line 1 %v", row.Line, row.Fields, want)
+	}
+
+	_, err = reader.Next()
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 3 || rowErr.Raw != `{"to":` {
+		t.Fatalf("Next() error = %v, want a RowError for line 3", err)
+	}
+
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Next() after the last row error = %v, want io.EOF", err)
+	}
+}
+
+func TestNewRowReader(t *testing.T) {
+	if _, err := NewRowReader(FormatCSV, strings.NewReader("")); err == nil {
+		t.Error("NewRowReader() accepted an empty CSV file")
+	}
+	if _, err := NewRowReader("xlsx", strings.NewReader("")); err == nil {
+		t.Error("NewRowReader() accepted an unknown format")
+	}
+}
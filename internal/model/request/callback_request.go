@@ -0,0 +1,46 @@
+package request
+
+import (
+	"fmt"
+	"time"
+
+	"auto-message-sender/internal/validator"
+)
+
+// DeliveryReceiptRequest is the delivery report the provider posts for a
+// message it accepted earlier.
+type DeliveryReceiptRequest struct {
+	MessageID string `json:"messageId" validate:"required" example:"67f2f8a8-ea58-4ed0-a6f9-ff217df4d849"`
+	Status    string `json:"status" validate:"required,oneof=delivered undelivered" example:"delivered"`
+	Timestamp string `json:"timestamp,omitempty" example:"2025-01-02T15:04:05Z"`
+	Error     string `json:"error,omitempty"`
+	// Provider is the webhook provider that sent the receipt, taken from the
+	// callback path. Message IDs are only unique within one provider.
+	Provider string `json:"-"`
+}
+
+func (r *DeliveryReceiptRequest) Validate() error {
+	if r.MessageID == "" {
+		return fmt.Errorf("messageId is required")
+	}
+	if err := validator.ValidateDeliveryStatus(r.Status); err != nil {
+		return err
+	}
+	if r.Timestamp != "" {
+		if _, err := time.Parse(time.RFC3339, r.Timestamp); err != nil {
+			return fmt.Errorf("timestamp must be in RFC3339 format")
+		}
+	}
+
+	return nil
+}
+
+// ReportedAt returns the provider's delivery time, or now when the receipt
+// does not carry one.
+func (r *DeliveryReceiptRequest) ReportedAt() time.Time {
+	if r.Timestamp == "" {
+		return time.Now()
+	}
+	t, _ := time.Parse(time.RFC3339, r.Timestamp)
+	return t
+}
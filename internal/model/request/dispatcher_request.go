@@ -0,0 +1,43 @@
+package request
+
+import (
+	"fmt"
+
+	"auto-message-sender/internal/validator"
+)
+
+type UpdateDispatcherRequest struct {
+	Interval   *string `json:"interval,omitempty" example:"30s"`
+	BatchSize  *int    `json:"batch_size,omitempty" example:"10"`
+	MaxPerTick *int    `json:"max_per_tick,omitempty" example:"100"`
+	Workers    *int    `json:"workers,omitempty" example:"8"`
+}
+
+func (r *UpdateDispatcherRequest) Validate() error {
+	if r.Interval == nil && r.BatchSize == nil && r.MaxPerTick == nil && r.Workers == nil {
+		return fmt.Errorf("at least one of interval, batch_size, max_per_tick or workers is required")
+	}
+
+	if r.Interval != nil {
+		if err := validator.ValidateDispatcherInterval(*r.Interval); err != nil {
+			return err
+		}
+	}
+	if r.BatchSize != nil {
+		if err := validator.ValidateDispatcherBatchSize(*r.BatchSize); err != nil {
+			return err
+		}
+	}
+	if r.MaxPerTick != nil {
+		if err := validator.ValidateDispatcherMaxPerTick(*r.MaxPerTick); err != nil {
+			return err
+		}
+	}
+	if r.Workers != nil {
+		if err := validator.ValidateDispatcherWorkers(*r.Workers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
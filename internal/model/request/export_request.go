@@ -0,0 +1,16 @@
+package request
+
+import "auto-message-sender/internal/validator"
+
+type ExportMessagesRequest struct {
+	MessageFilterRequest
+	Format string `query:"format"`
+}
+
+func (r *ExportMessagesRequest) Validate() error {
+	if err := validator.ValidateExportFormat(r.Format); err != nil {
+		return err
+	}
+
+	return r.ValidateFilters()
+}
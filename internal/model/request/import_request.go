@@ -0,0 +1,47 @@
+package request
+
+import (
+	"fmt"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/validator"
+)
+
+type ImportMessagesRequest struct {
+	Format        string `query:"format"`
+	ToColumn      string `query:"to_column"`
+	ContentColumn string `query:"content_column"`
+	SubjectColumn string `query:"subject_column"`
+	HTMLColumn    string `query:"html_column"`
+	Template      string `query:"template"`
+	Priority      string `query:"priority"`
+	Channel       string `query:"channel"`
+}
+
+func (r *ImportMessagesRequest) Validate() error {
+	if err := validator.ValidateImportFormat(r.Format); err != nil {
+		return err
+	}
+
+	if r.ToColumn == "" {
+		return fmt.Errorf("to_column is required")
+	}
+
+	if r.Template == "" && r.ContentColumn == "" {
+		return fmt.Errorf("either content_column or template is required")
+	}
+
+	if err := validator.ValidateContentTemplate(r.Template); err != nil {
+		return err
+	}
+
+	if err := validator.ValidatePriority(r.Priority); err != nil {
+		return err
+	}
+
+	if err := validator.ValidateChannel(r.Channel, config.AppSettings.EnabledChannels()); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,36 +1,291 @@
 package request
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
 	"auto-message-sender/internal/validator"
 )
 
 type SendMessageRequest struct {
-	To      string `json:"to" validate:"required,e164"`
-	Content string `json:"content" validate:"required,max=160"`
+	To       string `json:"to" validate:"required"`
+	Content  string `json:"content"`
+	Subject  string `json:"subject,omitempty" example:"Your order has shipped"`
+	HTML     string `json:"html,omitempty" example:"<p>Your order has shipped</p>"`
+	SendAt   string `json:"send_at,omitempty" example:"2025-01-02T15:04:05+03:00"`
+	Priority string `json:"priority,omitempty" validate:"omitempty,oneof=high normal bulk" example:"normal"`
+	Channel  string `json:"channel,omitempty" example:"webhook"`
 }
 
 func (r *SendMessageRequest) Validate() error {
-	if err := validator.ValidateMessageContent(r.Content); err != nil {
+	if err := validator.ValidateChannel(r.Channel, config.AppSettings.EnabledChannels()); err != nil {
+		return err
+	}
+
+	if err := validator.ValidateMessageBody(r.DeliveryChannel(), r.Subject, r.Content, r.HTML); err != nil {
+		return err
+	}
+
+	if err := validator.ValidateRecipient(r.DeliveryChannel(), r.To); err != nil {
+		return err
+	}
+
+	if err := validator.ValidateSendAt(r.SendAt, config.AppSettings.Scheduling.MaxAhead); err != nil {
 		return err
 	}
 
-	if err := validator.ValidatePhoneNumber(r.To); err != nil {
+	if err := validator.ValidatePriority(r.Priority); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// DeliveryChannel returns the requested channel, or the configured default
+// when none was given.
+func (r *SendMessageRequest) DeliveryChannel() string {
+	if r.Channel == "" {
+		return config.AppSettings.Channels.Default
+	}
+	return r.Channel
+}
+
+// ScheduledAt returns the parsed send_at in UTC, or nil when the message
+// should go out on the next dispatch. It assumes Validate has passed.
+func (r *SendMessageRequest) ScheduledAt() *time.Time {
+	if r.SendAt == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, r.SendAt)
+	if err != nil {
+		return nil
+	}
+
+	t = t.UTC()
+	return &t
+}
+
+type UpdateMessageRequest struct {
+	To      *string `json:"to,omitempty" example:"+905551111111"`
+	Content *string `json:"content,omitempty" example:"Updated content"`
+	Subject *string `json:"subject,omitempty" example:"Updated subject"`
+	HTML    *string `json:"html,omitempty" example:"<p>Updated content</p>"`
+	SendAt  *string `json:"send_at,omitempty" example:"2025-01-02T15:04:05+03:00"`
+}
+
+func (r *UpdateMessageRequest) Validate() error {
+	if r.To == nil && r.Content == nil && r.Subject == nil && r.HTML == nil && r.SendAt == nil {
+		return fmt.Errorf("at least one of to, content, subject, html or send_at is required")
+	}
+
+	if r.SendAt != nil {
+		if err := validator.ValidateSendAt(*r.SendAt, config.AppSettings.Scheduling.MaxAhead); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ChangesBody reports whether the update touches the recipient or content,
+// which are validated against the stored message with ValidateFor.
+func (r *UpdateMessageRequest) ChangesBody() bool {
+	return r.To != nil || r.Content != nil || r.Subject != nil || r.HTML != nil
+}
+
+// ValidateFor validates the recipient and content of message as they will be
+// stored after the update, so that a change to one field cannot leave the
+// rest of the body invalid for the message's channel.
+func (r *UpdateMessageRequest) ValidateFor(message *entity.Message) error {
+	if r.To != nil {
+		if err := validator.ValidateRecipient(message.Channel, *r.To); err != nil {
+			return err
+		}
+	}
+
+	subject, content, html := message.Subject, message.Content, message.HTML
+	if r.Subject != nil {
+		subject = *r.Subject
+	}
+	if r.Content != nil {
+		content = *r.Content
+	}
+	if r.HTML != nil {
+		html = *r.HTML
+	}
+	return validator.ValidateMessageBody(message.Channel, subject, content, html)
+}
+
+// Updates returns the columns to change. An empty send_at clears the
+// schedule so the message goes out on the next dispatch.
+func (r *UpdateMessageRequest) Updates() map[string]interface{} {
+	updates := make(map[string]interface{})
+	if r.To != nil {
+		updates["to"] = *r.To
+	}
+	if r.Content != nil {
+		updates["content"] = *r.Content
+	}
+	if r.Subject != nil {
+		updates["subject"] = *r.Subject
+	}
+	if r.HTML != nil {
+		updates["html"] = *r.HTML
+	}
+	if r.SendAt != nil {
+		updates["send_at"] = (&SendMessageRequest{SendAt: *r.SendAt}).ScheduledAt()
+	}
+	return updates
+}
+
+type SendMessageBatchRequest []SendMessageRequest
+
+func (r SendMessageBatchRequest) Validate() error {
+	return validator.ValidateBatchLength(len(r), config.AppSettings.Batch.MaxItems)
+}
+
+const (
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+
+	// StatusAll disables status filtering.
+	StatusAll = "all"
+
+	DefaultMessageSort = "-sent_at"
+)
+
+// MessageSortFields are the columns GET /messages can be sorted by. A leading
+// "-" in the sort parameter sorts descending.
+var MessageSortFields = []string{"sent_at", "created_at", "send_at", "to", "status", "priority"}
+
 type MessageFilterRequest struct {
-	Status    string `query:"status" validate:"omitempty,oneof=pending sent failed"`
-	StartDate string `query:"start_date" validate:"omitempty,datetime=2006-01-02"`
-	EndDate   string `query:"end_date" validate:"omitempty,datetime=2006-01-02"`
-	Page      int    `query:"page" validate:"min=1"`
-	PageSize  int    `query:"page_size" validate:"min=1,max=100"`
+	Status      []string `query:"status"`
+	To          string   `query:"to"`
+	ToPrefix    string   `query:"to_prefix"`
+	Content     string   `query:"content" validate:"omitempty,max=160"`
+	MessageID   string   `query:"message_id"`
+	Channel     string   `query:"channel"`
+	Provider    string   `query:"provider"`
+	StartDate   string   `query:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	EndDate     string   `query:"end_date" validate:"omitempty,datetime=2006-01-02"`
+	CreatedFrom string   `query:"created_from"`
+	CreatedTo   string   `query:"created_to"`
+	Sort        string   `query:"sort"`
+	Page        int      `query:"page" validate:"min=1"`
+	PageSize    int      `query:"page_size" validate:"min=1,max=100"`
+	Pagination  string   `query:"pagination" validate:"omitempty,oneof=offset cursor"`
+	Cursor      string   `query:"cursor"`
+}
+
+// CursorMode reports whether keyset paging was requested, either explicitly
+// or by passing a cursor from a previous page.
+func (r *MessageFilterRequest) CursorMode() bool {
+	return r.Pagination == PaginationCursor || r.Cursor != ""
+}
+
+// Statuses returns the statuses to filter on. Status may be repeated or
+// comma separated; without one the messages handed to the provider are
+// listed, and "all" lists every status, in which case nil is returned.
+func (r *MessageFilterRequest) Statuses() []string {
+	var statuses []string
+	for _, value := range r.Status {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status == StatusAll {
+				return nil
+			}
+			if status != "" && !slices.Contains(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+	}
+
+	if len(statuses) == 0 {
+		return slices.Clone(entity.HandedOffStatuses)
+	}
+	return statuses
+}
+
+// SortOrder returns the sort field and whether it is descending.
+func (r *MessageFilterRequest) SortOrder() (string, bool) {
+	sort := r.Sort
+	if sort == "" {
+		sort = DefaultMessageSort
+	}
+	return strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+}
+
+// CreatedRange returns the created_at window as [from, until). Zero values
+// mean the bound is open. A date-only created_to includes the whole day.
+func (r *MessageFilterRequest) CreatedRange() (from, until time.Time) {
+	if r.CreatedFrom != "" {
+		from, _ = parseFilterTime(r.CreatedFrom)
+	}
+	if r.CreatedTo != "" {
+		var dateOnly bool
+		until, dateOnly = parseFilterTime(r.CreatedTo)
+		if dateOnly {
+			until = until.AddDate(0, 0, 1)
+		} else {
+			// created_at is stored with microsecond precision.
+			until = until.Add(time.Microsecond)
+		}
+	}
+	return from, until
+}
+
+func parseFilterTime(value string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true
+	}
+	t, _ := time.Parse(time.RFC3339, value)
+	return t, false
 }
 
 func (r *MessageFilterRequest) Validate() error {
-	if err := validator.ValidateStatus(r.Status); err != nil {
+	if err := r.ValidateFilters(); err != nil {
+		return err
+	}
+	if err := validator.ValidatePageParams(r.Page, r.PageSize); err != nil {
+		return err
+	}
+	if err := validator.ValidatePagination(r.Pagination, r.Cursor); err != nil {
+		return err
+	}
+	if r.CursorMode() && r.Sort != "" && r.Sort != DefaultMessageSort {
+		return fmt.Errorf("cursor pagination only supports sort=%s", DefaultMessageSort)
+	}
+
+	return nil
+}
+
+// ValidateFilters validates the search and sort parameters without the
+// paging ones, for callers such as the export that read every match.
+func (r *MessageFilterRequest) ValidateFilters() error {
+	if err := validator.ValidateStatuses(r.Status, StatusAll); err != nil {
+		return err
+	}
+	if r.To != "" {
+		channel := entity.ChannelWebhook
+		if strings.Contains(r.To, "@") {
+			channel = entity.ChannelEmail
+		}
+		if err := validator.ValidateRecipient(channel, r.To); err != nil {
+			return err
+		}
+	}
+	if err := validator.ValidatePhonePrefix(r.ToPrefix); err != nil {
+		return err
+	}
+	if err := validator.ValidateContentSearch(r.Content); err != nil {
+		return err
+	}
+	if err := validator.ValidateChannel(r.Channel, entity.Channels); err != nil {
 		return err
 	}
 	if err := validator.ValidateDate(r.StartDate); err != nil {
@@ -42,7 +297,10 @@ func (r *MessageFilterRequest) Validate() error {
 	if err := validator.ValidateDateRange(r.StartDate, r.EndDate); err != nil {
 		return err
 	}
-	if err := validator.ValidatePageParams(r.Page, r.PageSize); err != nil {
+	if err := validator.ValidateTimeRange(r.CreatedFrom, r.CreatedTo); err != nil {
+		return err
+	}
+	if err := validator.ValidateSort(r.Sort, MessageSortFields); err != nil {
 		return err
 	}
 
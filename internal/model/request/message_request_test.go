@@ -0,0 +1,57 @@
+package request
+
+import (
+	"testing"
+
+	"auto-message-sender/internal/entity"
+)
+
+func TestUpdateMessageRequestValidateFor(t *testing.T) {
+	email := &entity.Message{
+		Channel: entity.ChannelEmail,
+		To:      "user@example.com",
+		Subject: "Hi",
+		Content: "Hello",
+	}
+	emailWithHTML := &entity.Message{
+		Channel: entity.ChannelEmail,
+		To:      "user@example.com",
+		Subject: "Hi",
+		Content: "Hello",
+		HTML:    "<p>Hello</p>",
+	}
+	sms := &entity.Message{
+		Channel: entity.ChannelWebhook,
+		To:      "+905551234567",
+		Content: "Hello",
+	}
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		message *entity.Message
+		req     UpdateMessageRequest
+		wantErr bool
+	}{
+		{"email subject only", email, UpdateMessageRequest{Subject: ptr("Updated")}, false},
+		{"email content only", email, UpdateMessageRequest{Content: ptr("Updated")}, false},
+		{"email clear content without html", email, UpdateMessageRequest{Content: ptr("")}, true},
+		{"email clear content with html", emailWithHTML, UpdateMessageRequest{Content: ptr("")}, false},
+		{"email clear content and html", emailWithHTML, UpdateMessageRequest{Content: ptr(""), HTML: ptr("")}, true},
+		{"email clear subject", email, UpdateMessageRequest{Subject: ptr("")}, true},
+		{"email phone number", email, UpdateMessageRequest{To: ptr("+905551234567")}, true},
+		{"webhook content", sms, UpdateMessageRequest{Content: ptr("Updated")}, false},
+		{"webhook empty content", sms, UpdateMessageRequest{Content: ptr("")}, true},
+		{"webhook subject", sms, UpdateMessageRequest{Subject: ptr("Hi")}, true},
+		{"webhook recipient", sms, UpdateMessageRequest{To: ptr("+905551111111")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.ValidateFor(tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
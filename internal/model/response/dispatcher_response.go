@@ -0,0 +1,18 @@
+package response
+
+type DispatcherResponse struct {
+	Interval   string             `json:"interval"`
+	BatchSize  int                `json:"batch_size"`
+	MaxPerTick int                `json:"max_per_tick"`
+	Workers    int                `json:"workers"`
+	Running    bool               `json:"running"`
+	Pool       WorkerPoolResponse `json:"pool"`
+}
+
+type WorkerPoolResponse struct {
+	Workers     int     `json:"workers"`
+	Busy        int     `json:"busy"`
+	Queued      int     `json:"queued"`
+	Processed   uint64  `json:"processed"`
+	Utilisation float64 `json:"utilisation"`
+}
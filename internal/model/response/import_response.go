@@ -0,0 +1,15 @@
+package response
+
+type ImportResponse struct {
+	ImportID       string `json:"import_id"`
+	Format         string `json:"format"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	Total          int    `json:"total"`
+	Accepted       int    `json:"accepted"`
+	Rejected       int    `json:"rejected"`
+	Duplicates     int    `json:"duplicates"`
+	StartedAt      string `json:"started_at"`
+	FinishedAt     string `json:"finished_at"`
+	ErrorReportURL string `json:"error_report_url"`
+}
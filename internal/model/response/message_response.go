@@ -5,21 +5,81 @@ type MessageResponse struct {
 	MessageID string `json:"messageId"`
 }
 
+type BatchMessageResponse struct {
+	Accepted int                  `json:"accepted"`
+	Rejected int                  `json:"rejected"`
+	Results  []BatchMessageResult `json:"results"`
+}
+
+type BatchMessageResult struct {
+	Index     int    `json:"index"`
+	Status    string `json:"status"`
+	MessageID string `json:"messageId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MessageListResponse carries total, page and total_pages in offset mode and
+// next_cursor in cursor mode.
 type MessageListResponse struct {
 	Messages   []MessageItem `json:"messages"`
-	Total      int           `json:"total"`
-	Page       int           `json:"page"`
+	Total      *int64        `json:"total,omitempty"`
+	Page       *int          `json:"page,omitempty"`
 	PageSize   int           `json:"page_size"`
-	TotalPages int           `json:"total_pages"`
+	TotalPages *int          `json:"total_pages,omitempty"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
 
 type MessageItem struct {
 	ID        string `json:"id"`
 	To        string `json:"to"`
 	Content   string `json:"content"`
+	Subject   string `json:"subject,omitempty"`
 	Status    string `json:"status"`
 	MessageID string `json:"message_id,omitempty"`
+	Provider  string `json:"provider,omitempty"`
 	SentAt    string `json:"sent_at,omitempty"`
+	SendAt    string `json:"send_at,omitempty"`
+	Priority  string `json:"priority"`
+	Channel   string `json:"channel"`
+}
+
+type MessageDetailResponse struct {
+	ID                 string `json:"id"`
+	To                 string `json:"to"`
+	Content            string `json:"content"`
+	Subject            string `json:"subject,omitempty"`
+	HTML               string `json:"html,omitempty"`
+	Status             string `json:"status"`
+	Priority           string `json:"priority"`
+	Channel            string `json:"channel"`
+	AttemptCount       int    `json:"attempt_count"`
+	LastError          string `json:"last_error,omitempty"`
+	NextAttemptAt      string `json:"next_attempt_at,omitempty"`
+	SendAt             string `json:"send_at,omitempty"`
+	ClaimedBy          string `json:"claimed_by,omitempty"`
+	ClaimedUntil       string `json:"claimed_until,omitempty"`
+	MessageID          string `json:"message_id,omitempty"`
+	Provider           string `json:"provider,omitempty"`
+	SentAt             string `json:"sent_at,omitempty"`
+	CachedSentAt       string `json:"cached_sent_at,omitempty"`
+	DeliveryReportedAt string `json:"delivery_reported_at,omitempty"`
+	DeliveryError      string `json:"delivery_error,omitempty"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+}
+
+type MessageEventListResponse struct {
+	MessageID string             `json:"message_id"`
+	Events    []MessageEventItem `json:"events"`
+}
+
+type MessageEventItem struct {
+	Type         string `json:"type" example:"retry_scheduled"`
+	Status       string `json:"status" example:"pending"`
+	AttemptCount int    `json:"attempt_count"`
+	Detail       string `json:"detail,omitempty"`
+	Error        string `json:"error,omitempty"`
+	CreatedAt    string `json:"created_at"`
 }
 
 type ErrorResponse struct {
@@ -0,0 +1,17 @@
+package response
+
+type RateLimitResponse struct {
+	Enabled   bool                               `json:"enabled"`
+	Providers map[string]RateLimitBucketResponse `json:"providers"`
+	Recipient RateLimitBucketResponse            `json:"recipient"`
+}
+
+type RateLimitBucketResponse struct {
+	Limit    int      `json:"limit"`
+	Period   string   `json:"period"`
+	Burst    int      `json:"burst"`
+	Tokens   *float64 `json:"tokens,omitempty"`
+	Allowed  uint64   `json:"allowed"`
+	Deferred uint64   `json:"deferred"`
+	Errors   uint64   `json:"errors"`
+}
@@ -1,6 +0,0 @@
-package response
-
-type WebhookResponse struct {
-	Message   string `json:"message"`
-	MessageID string `json:"messageId"`
-}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// messageCursor is the keyset position of the last message of a page. It is
+// handed to clients as opaque base64 so its shape can change freely.
+type messageCursor struct {
+	SentAt time.Time `json:"s"`
+	ID     uuid.UUID `json:"i"`
+}
+
+func encodeCursor(c messageCursor) string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCursor(value string) (messageCursor, error) {
+	var c messageCursor
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &c); err != nil || c.ID == uuid.Nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
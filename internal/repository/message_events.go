@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"auto-message-sender/internal/entity"
+)
+
+func newEvent(message *entity.Message, eventType, detail, eventError string) entity.MessageEvent {
+	return entity.MessageEvent{
+		MessageID:    message.ID,
+		Type:         eventType,
+		Status:       message.Status,
+		AttemptCount: message.AttemptCount,
+		Detail:       detail,
+		Error:        eventError,
+	}
+}
+
+// recordEvents appends events to the message_events table. It must be called
+// with the transaction of the state change the events describe.
+func recordEvents(tx *gorm.DB, events ...entity.MessageEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+func changedFields(updates map[string]interface{}) string {
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return "changed " + strings.Join(fields, ", ")
+}
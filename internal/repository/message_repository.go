@@ -1,6 +1,11 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"auto-message-sender/internal/entity"
@@ -8,14 +13,45 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrMessageNotPending is returned when a message can no longer be changed
+// because it has left the pending state or is currently claimed for sending.
+var ErrMessageNotPending = errors.New("message is not pending or is being sent")
+
+// ErrClaimLost is returned when a claimed message is updated by an instance
+// whose lease has expired and that no longer holds the claim.
+var ErrClaimLost = errors.New("message is no longer claimed by this instance")
+
+// ErrMessageNotSent is returned when a delivery receipt arrives for a message
+// that was never accepted by the provider.
+var ErrMessageNotSent = errors.New("message has not been sent")
+
 type MessageRepository interface {
 	Create(message *entity.Message) error
-	GetUnsentMessages(limit int) ([]entity.Message, error)
-	UpdateStatus(messageID, status string, sentAt time.Time) error
-	UpdateMessageID(id uuid.UUID, messageID string) error
-	GetMessages(filter *request.MessageFilterRequest) ([]entity.Message, error)
+	CreateBatch(messages []entity.Message, batchSize int) error
+	GetByID(id uuid.UUID) (*entity.Message, error)
+	UpdatePending(id uuid.UUID, updates map[string]interface{}) (*entity.Message, error)
+	Cancel(id uuid.UUID) (*entity.Message, error)
+	ClaimPendingMessages(owner, priority string, channels []string, limit int, lease time.Duration) ([]entity.Message, error)
+	ScheduleRetry(id uuid.UUID, owner, lastError string, nextAttemptAt time.Time) error
+	MarkFailed(id uuid.UUID, owner, lastError string) error
+	DeferClaim(id uuid.UUID, owner, reason string, nextAttemptAt time.Time) error
+	MarkSent(id uuid.UUID, owner, provider, providerID string, sentAt time.Time) error
+	RecoverInterruptedSends(owner string) (*RecoveryResult, error)
+	UpdateDelivery(providers []string, messageID, status string, reportedAt time.Time, reason string) (*entity.Message, error)
+	GetEvents(id uuid.UUID) ([]entity.MessageEvent, error)
+	GetMessages(filter *request.MessageFilterRequest) (*MessagePage, error)
+	StreamMessages(ctx context.Context, filter *request.MessageFilterRequest, fn func(*entity.Message) error) error
+}
+
+// MessagePage is one page of GetMessages. Total is only counted in offset
+// mode; in cursor mode NextCursor is empty on the last page.
+type MessagePage struct {
+	Messages   []entity.Message
+	Total      int64
+	NextCursor string
 }
 
 type messageRepository struct {
@@ -27,55 +63,546 @@ func NewMessageRepository(db *gorm.DB) MessageRepository {
 }
 
 func (r *messageRepository) Create(message *entity.Message) error {
-	message.ID = uuid.New()
-	return r.db.Create(message).Error
+	if message.ID == uuid.Nil {
+		message.ID = uuid.New()
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return recordEvents(tx, newEvent(message, entity.EventCreated, "", ""))
+	})
+}
+
+func (r *messageRepository) CreateBatch(messages []entity.Message, batchSize int) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	events := make([]entity.MessageEvent, len(messages))
+	for i := range messages {
+		if messages[i].ID == uuid.Nil {
+			messages[i].ID = uuid.New()
+		}
+		events[i] = newEvent(&messages[i], entity.EventCreated, "", "")
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&messages, batchSize).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(&events, batchSize).Error
+	})
+}
+
+func (r *messageRepository) GetByID(id uuid.UUID) (*entity.Message, error) {
+	var message entity.Message
+	if err := r.db.Where("id = ?", id).First(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// UpdatePending applies updates only while the message is pending and not
+// claimed. A concurrent claim holds the row lock, so this update waits for it
+// and then sees the claim instead of racing the send.
+func (r *messageRepository) UpdatePending(id uuid.UUID, updates map[string]interface{}) (*entity.Message, error) {
+	return r.updatePending(id, updates, entity.EventUpdated)
+}
+
+func (r *messageRepository) updatePending(id uuid.UUID, updates map[string]interface{}, eventType string) (*entity.Message, error) {
+	var message entity.Message
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Message{}).
+			Where("id = ? AND status = ?", id, entity.StatusPending).
+			Where("(claimed_until IS NULL OR claimed_until < NOW())").
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if err := tx.Where("id = ?", id).First(&message).Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return ErrMessageNotPending
+		}
+		return recordEvents(tx, newEvent(&message, eventType, changedFields(updates), ""))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (r *messageRepository) Cancel(id uuid.UUID) (*entity.Message, error) {
+	return r.updatePending(id, map[string]interface{}{
+		"status":          entity.StatusCancelled,
+		"next_attempt_at": nil,
+	}, entity.EventCancelled)
 }
 
-func (r *messageRepository) GetUnsentMessages(limit int) ([]entity.Message, error) {
+// ClaimPendingMessages leases up to limit pending messages to owner. Rows are
+// selected with FOR UPDATE SKIP LOCKED so concurrent instances never pick the
+// same message, and the lease lets another instance take over once it expires.
+// Lease times use the database clock so instances with skewed clocks agree.
+// An empty priority claims from every lane, most urgent first. Only messages
+// of the given channels are claimed; the rest wait until their channel is
+// available again.
+func (r *messageRepository) ClaimPendingMessages(owner, priority string, channels []string, limit int, lease time.Duration) ([]entity.Message, error) {
 	var messages []entity.Message
-	err := r.db.Where("status = ?", entity.StatusPending).Limit(limit).Find(&messages).Error
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Scopes(claimable).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		if priority != "" {
+			query = query.Where("priority = ?", priority)
+		} else {
+			query = query.Order(priorityRank)
+		}
+		query = query.Where("channel IN ?", channels)
+
+		err := query.
+			Order("created_at").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i, msg := range messages {
+			ids[i] = msg.ID
+		}
+
+		var claimedUntil time.Time
+		err = tx.Raw("SELECT NOW() + ?::interval", fmt.Sprintf("%d milliseconds", lease.Milliseconds())).
+			Scan(&claimedUntil).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entity.Message{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"claimed_by":    owner,
+				"claimed_until": claimedUntil,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		events := make([]entity.MessageEvent, len(messages))
+		for i := range messages {
+			messages[i].ClaimedBy = owner
+			messages[i].ClaimedUntil = &claimedUntil
+			events[i] = newEvent(&messages[i], entity.EventClaimed,
+				fmt.Sprintf("claimed by %s until %s", owner, claimedUntil.Format(time.RFC3339)), "")
+		}
+		return recordEvents(tx, events...)
+	})
+
 	return messages, err
 }
 
-func (r *messageRepository) GetMessages(filter *request.MessageFilterRequest) ([]entity.Message, error) {
-	var messages []entity.Message
-	query := r.db.Model(&entity.Message{})
+func (r *messageRepository) ScheduleRetry(id uuid.UUID, owner, lastError string, nextAttemptAt time.Time) error {
+	return r.updateClaimed(id, owner, map[string]interface{}{
+		"attempt_count":   gorm.Expr("attempt_count + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+		"claimed_by":      "",
+		"claimed_until":   nil,
+	}, entity.EventRetryScheduled, "next attempt at "+nextAttemptAt.Format(time.RFC3339), lastError)
+}
 
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
-	} else {
-		query = query.Where("status = ?", entity.StatusSent)
-	}
+func (r *messageRepository) MarkFailed(id uuid.UUID, owner, lastError string) error {
+	return r.updateClaimed(id, owner, map[string]interface{}{
+		"status":          entity.StatusFailed,
+		"attempt_count":   gorm.Expr("attempt_count + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nil,
+		"claimed_by":      "",
+		"claimed_until":   nil,
+	}, entity.EventFailed, "", lastError)
+}
+
+// DeferClaim gives a claimed message back to be sent at nextAttemptAt without
+// counting an attempt, for sends held back by a rate limit or an open circuit.
+func (r *messageRepository) DeferClaim(id uuid.UUID, owner, reason string, nextAttemptAt time.Time) error {
+	return r.updateClaimed(id, owner, map[string]interface{}{
+		"next_attempt_at": nextAttemptAt,
+		"claimed_by":      "",
+		"claimed_until":   nil,
+	}, entity.EventDeferred, reason+", next attempt at "+nextAttemptAt.Format(time.RFC3339), "")
+}
+
+// updateClaimed applies updates to a pending message claimed by owner and
+// records the event with the resulting status and attempt count in the same
+// transaction. An owner whose lease expired may have lost the message to
+// another instance, so the update is refused with ErrClaimLost instead of
+// overwriting the new claim.
+func (r *messageRepository) updateClaimed(id uuid.UUID, owner string, updates map[string]interface{}, eventType, detail, eventError string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Message{}).
+			Where("id = ? AND status = ? AND claimed_by = ?", id, entity.StatusPending, owner).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrClaimLost
+		}
+
+		var message entity.Message
+		if err := tx.Where("id = ?", id).First(&message).Error; err != nil {
+			return err
+		}
+		return recordEvents(tx, newEvent(&message, eventType, detail, eventError))
+	})
+}
+
+func claimable(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", entity.StatusPending).
+		Scopes(due).
+		Where("(claimed_until IS NULL OR claimed_until < NOW())").
+		Where("(next_attempt_at IS NULL OR next_attempt_at <= NOW())")
+}
 
-	if filter.StartDate != "" {
-		startDate, _ := time.Parse("2006-01-02", filter.StartDate)
-		query = query.Where("sent_at >= ?", startDate)
+var priorityRank = fmt.Sprintf("CASE priority WHEN '%s' THEN 0 WHEN '%s' THEN 1 ELSE 2 END",
+	entity.PriorityHigh, entity.PriorityNormal)
+
+func due(db *gorm.DB) *gorm.DB {
+	return db.Where("(send_at IS NULL OR send_at <= NOW())")
+}
+
+func (r *messageRepository) GetMessages(filter *request.MessageFilterRequest) (*MessagePage, error) {
+	query := r.db.Model(&entity.Message{}).Scopes(messageFilter(filter)).Session(&gorm.Session{})
+
+	if filter.CursorMode() {
+		return r.getMessagesAfterCursor(query, filter)
 	}
 
-	if filter.EndDate != "" {
-		endDate, _ := time.Parse("2006-01-02", filter.EndDate)
-		endDatePlusDay := endDate.AddDate(0, 0, 1)
-		query = query.Where("sent_at < ?", endDatePlusDay)
+	page := &MessagePage{}
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
 	}
 
 	offset := (filter.Page - 1) * filter.PageSize
-	query = query.Offset(offset).Limit(filter.PageSize)
+	err := query.Offset(offset).Limit(filter.PageSize).
+		Scopes(messageSort(filter)).
+		Find(&page.Messages).Error
+	if err != nil {
+		return nil, err
+	}
 
-	err := query.Order("sent_at DESC").Find(&messages).Error
-	return messages, err
+	return page, nil
 }
 
-func (r *messageRepository) UpdateStatus(messageID, status string, sentAt time.Time) error {
-	return r.db.Model(&entity.Message{}).
-		Where("message_id = ?", messageID).
-		Updates(map[string]interface{}{
-			"status":  status,
-			"sent_at": sentAt,
-		}).Error
+// getMessagesAfterCursor pages on (sent_at, id) so each page is an index range
+// scan regardless of how deep the client has paged.
+func (r *messageRepository) getMessagesAfterCursor(query *gorm.DB, filter *request.MessageFilterRequest) (*MessagePage, error) {
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(sent_at, id) < (?, ?)", cursor.SentAt, cursor.ID)
+	}
+
+	page := &MessagePage{}
+	err := query.Limit(filter.PageSize + 1).
+		Order("sent_at DESC").Order("id DESC").
+		Find(&page.Messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Messages) > filter.PageSize {
+		page.Messages = page.Messages[:filter.PageSize]
+		last := page.Messages[len(page.Messages)-1]
+		page.NextCursor = encodeCursor(messageCursor{SentAt: last.SentAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// StreamMessages calls fn for every message matching filter, reading rows
+// from a database cursor one at a time instead of loading the result set.
+// Paging parameters are ignored. Iteration stops at the first error from fn.
+func (r *messageRepository) StreamMessages(ctx context.Context, filter *request.MessageFilterRequest, fn func(*entity.Message) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&entity.Message{}).
+		Scopes(messageFilter(filter), messageSort(filter)).
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message entity.Message
+		if err := r.db.ScanRows(rows, &message); err != nil {
+			return err
+		}
+		if err := fn(&message); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func messageFilter(filter *request.MessageFilterRequest) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if statuses := filter.Statuses(); len(statuses) > 0 {
+			query = query.Where("status IN ?", statuses)
+		}
+
+		if filter.To != "" {
+			query = query.Where(`"to" = ?`, filter.To)
+		}
+		if filter.ToPrefix != "" {
+			query = query.Where(`"to" LIKE ?`, escapeLike(filter.ToPrefix)+"%")
+		}
+		if filter.Content != "" {
+			query = query.Where("content ILIKE ?", "%"+escapeLike(filter.Content)+"%")
+		}
+		if filter.MessageID != "" {
+			query = query.Where("message_id = ?", filter.MessageID)
+		}
+		if filter.Channel != "" {
+			query = query.Where("channel = ?", filter.Channel)
+		}
+		if filter.Provider != "" {
+			query = query.Where("provider = ?", filter.Provider)
+		}
+
+		if filter.StartDate != "" {
+			startDate, _ := time.Parse("2006-01-02", filter.StartDate)
+			query = query.Where("sent_at >= ?", startDate)
+		}
+
+		if filter.EndDate != "" {
+			endDate, _ := time.Parse("2006-01-02", filter.EndDate)
+			endDatePlusDay := endDate.AddDate(0, 0, 1)
+			query = query.Where("sent_at < ?", endDatePlusDay)
+		}
+
+		createdFrom, createdUntil := filter.CreatedRange()
+		if !createdFrom.IsZero() {
+			query = query.Where("created_at >= ?", createdFrom)
+		}
+		if !createdUntil.IsZero() {
+			query = query.Where("created_at < ?", createdUntil)
+		}
+
+		return query
+	}
+}
+
+// messageSortColumns maps request.MessageSortFields to their SQL columns.
+var messageSortColumns = map[string]string{
+	"sent_at":    "sent_at",
+	"created_at": "created_at",
+	"send_at":    "send_at",
+	"to":         `"to"`,
+	"status":     "status",
+	"priority":   "priority",
+}
+
+// messageSort orders by the requested column with id as a tie-breaker so
+// offset pages are stable.
+func messageSort(filter *request.MessageFilterRequest) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		field, desc := filter.SortOrder()
+		column, ok := messageSortColumns[field]
+		if !ok {
+			column = "sent_at"
+		}
+
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+
+		return query.Order(column + " " + direction).Order("id " + direction)
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// MarkSent records that the provider accepted the message under providerID.
+// The provider ID, status and attempt bookkeeping are written in one
+// statement, and only while the message is pending and claimed by owner. A
+// message that another instance has completed since is reported with
+// ErrMessageNotPending, one it has claimed again with ErrClaimLost.
+func (r *messageRepository) MarkSent(id uuid.UUID, owner, provider, providerID string, sentAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Message{}).
+			Where("id = ? AND status = ? AND claimed_by = ?", id, entity.StatusPending, owner).
+			Updates(map[string]interface{}{
+				"status":          entity.StatusSent,
+				"message_id":      providerID,
+				"provider":        provider,
+				"sent_at":         sentAt,
+				"attempt_count":   gorm.Expr("attempt_count + 1"),
+				"last_error":      "",
+				"next_attempt_at": nil,
+				"claimed_by":      "",
+				"claimed_until":   nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var message entity.Message
+		if err := tx.Where("id = ?", id).First(&message).Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			if message.Status != entity.StatusPending {
+				return ErrMessageNotPending
+			}
+			return ErrClaimLost
+		}
+		return recordEvents(tx, newEvent(&message, entity.EventSent, fmt.Sprintf("provider %s, message ID %s", provider, providerID), ""))
+	})
+}
+
+// RecoveryResult counts the rows fixed by RecoverInterruptedSends.
+type RecoveryResult struct {
+	Completed int64
+	Released  int64
+}
+
+// RecoverInterruptedSends reconciles rows left half-updated by a crash. Older
+// releases stored the provider ID and the sent status in separate writes, so
+// a pending message that already has a provider ID was accepted by the
+// provider and is completed as sent, using its last update as the send time.
+// Leases still held under owner belong to a previous run of this instance and
+// are released so those messages do not wait for the lease to expire.
+func (r *messageRepository) RecoverInterruptedSends(owner string) (*RecoveryResult, error) {
+	result := &RecoveryResult{}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var halfSent []entity.Message
+		err := tx.Where("status = ? AND message_id <> ''", entity.StatusPending).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&halfSent).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range halfSent {
+			message := &halfSent[i]
+			err := tx.Model(message).Updates(map[string]interface{}{
+				"status":          entity.StatusSent,
+				"sent_at":         message.UpdatedAt,
+				"attempt_count":   gorm.Expr("attempt_count + 1"),
+				"last_error":      "",
+				"next_attempt_at": nil,
+				"claimed_by":      "",
+				"claimed_until":   nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+			message.Status = entity.StatusSent
+			message.AttemptCount++
+			err = recordEvents(tx, newEvent(message, entity.EventSent,
+				"recovered after restart, provider message ID "+message.MessageID, ""))
+			if err != nil {
+				return err
+			}
+		}
+		result.Completed = int64(len(halfSent))
+
+		var released []entity.Message
+		err = tx.Where("status = ? AND claimed_by = ?", entity.StatusPending, owner).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Find(&released).Error
+		if err != nil || len(released) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(released))
+		events := make([]entity.MessageEvent, len(released))
+		for i := range released {
+			ids[i] = released[i].ID
+			events[i] = newEvent(&released[i], entity.EventReleased, "lease of previous run of "+owner+" released", "")
+		}
+		err = tx.Model(&entity.Message{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"claimed_by":    "",
+				"claimed_until": nil,
+			}).Error
+		if err != nil {
+			return err
+		}
+		result.Released = int64(len(released))
+		return recordEvents(tx, events...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateDelivery records the delivery outcome of the message one of providers
+// knows as messageID. Provider message IDs are not unique, so when several
+// messages match, the most recently sent one takes the receipt. Receipts
+// older than the one already recorded are ignored so that out-of-order
+// callbacks cannot roll the status back.
+func (r *messageRepository) UpdateDelivery(providers []string, messageID, status string, reportedAt time.Time, reason string) (*entity.Message, error) {
+	var message entity.Message
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("message_id = ? AND provider IN ?", messageID, providers).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Order("sent_at DESC").
+			Take(&message).Error
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(entity.HandedOffStatuses, message.Status) {
+			return ErrMessageNotSent
+		}
+		if message.DeliveryReportedAt != nil && message.DeliveryReportedAt.After(reportedAt) {
+			return nil
+		}
+
+		err = tx.Model(&entity.Message{}).
+			Where("id = ?", message.ID).
+			Updates(map[string]interface{}{
+				"status":               status,
+				"delivery_reported_at": reportedAt,
+				"delivery_error":       reason,
+			}).Error
+		if err != nil {
+			return err
+		}
+		message.Status = status
+		message.DeliveryReportedAt = &reportedAt
+		message.DeliveryError = reason
+		return recordEvents(tx, newEvent(&message, status, "reported at "+reportedAt.Format(time.RFC3339), reason))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
 }
 
-func (r *messageRepository) UpdateMessageID(id uuid.UUID, messageID string) error {
-	return r.db.Model(&entity.Message{}).
-		Where("id = ?", id).
-		Update("message_id", messageID).Error
+func (r *messageRepository) GetEvents(id uuid.UUID) ([]entity.MessageEvent, error) {
+	var events []entity.MessageEvent
+	err := r.db.Where("message_id = ?", id).Order("created_at").Order("id").Find(&events).Error
+	return events, err
 }
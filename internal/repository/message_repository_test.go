@@ -0,0 +1,505 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"auto-message-sender/internal/entity"
+)
+
+// The repository relies on Postgres row locking, so these tests run against
+// the database in TEST_DATABASE_DSN and are skipped without one. They empty
+// the messages tables, so the database must be a disposable one.
+func newTestRepository(t *testing.T) (*messageRepository, *gorm.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB() error = %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&entity.Message{}, &entity.MessageEvent{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	if err := db.Exec("TRUNCATE messages, message_events").Error; err != nil {
+		t.Fatalf("TRUNCATE error = %v", err)
+	}
+
+	return &messageRepository{db: db}, db
+}
+
+func createMessage(t *testing.T, repo *messageRepository, message entity.Message) entity.Message {
+	t.Helper()
+
+	if message.To == "" {
+		message.To = "+905551234567"
+	}
+	if message.Content == "" {
+		message.Content = "Hello"
+	}
+	if message.Status == "" {
+		message.Status = entity.StatusPending
+	}
+	if message.Priority == "" {
+		message.Priority = entity.PriorityNormal
+	}
+	if message.Channel == "" {
+		message.Channel = entity.ChannelWebhook
+	}
+	if err := repo.Create(&message); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return message
+}
+
+func getMessage(t *testing.T, repo *messageRepository, id uuid.UUID) *entity.Message {
+	t.Helper()
+
+	message, err := repo.GetByID(id)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	return message
+}
+
+// expireLease moves the lease of a claimed message into the past, as if its
+// owner had stalled for longer than the lease duration.
+func expireLease(t *testing.T, db *gorm.DB, id uuid.UUID) {
+	t.Helper()
+
+	err := db.Model(&entity.Message{}).Where("id = ?", id).
+		Update("claimed_until", gorm.Expr("NOW() - INTERVAL '1 minute'")).Error
+	if err != nil {
+		t.Fatalf("expiring lease: %v", err)
+	}
+}
+
+func claimIDs(messages []entity.Message) map[uuid.UUID]bool {
+	ids := make(map[uuid.UUID]bool, len(messages))
+	for _, message := range messages {
+		ids[message.ID] = true
+	}
+	return ids
+}
+
+var webhookOnly = []string{entity.ChannelWebhook}
+
+func TestClaimPendingMessages(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	future := time.Now().Add(time.Hour)
+	due := []entity.Message{
+		createMessage(t, repo, entity.Message{}),
+		createMessage(t, repo, entity.Message{Priority: entity.PriorityHigh}),
+		createMessage(t, repo, entity.Message{Priority: entity.PriorityBulk}),
+	}
+	createMessage(t, repo, entity.Message{Status: entity.StatusSent, MessageID: "provider-1"})
+	createMessage(t, repo, entity.Message{SendAt: &future})
+	createMessage(t, repo, entity.Message{NextAttemptAt: &future})
+	createMessage(t, repo, entity.Message{Channel: entity.ChannelFile})
+
+	claimed, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if len(claimed) != len(due) {
+		t.Fatalf("claimed %d messages, want %d", len(claimed), len(due))
+	}
+	ids := claimIDs(claimed)
+	for _, message := range due {
+		if !ids[message.ID] {
+			t.Errorf("due message %s was not claimed", message.ID)
+		}
+	}
+	if claimed[0].Priority != entity.PriorityHigh {
+		t.Errorf("first claimed priority = %q, want %q", claimed[0].Priority, entity.PriorityHigh)
+	}
+
+	stored := getMessage(t, repo, due[0].ID)
+	if stored.ClaimedBy != "instance-a" || stored.ClaimedUntil == nil || !stored.ClaimedUntil.After(time.Now()) {
+		t.Errorf("stored claim = %q until %v, want instance-a in the future", stored.ClaimedBy, stored.ClaimedUntil)
+	}
+
+	again, err := repo.ClaimPendingMessages("instance-b", "", webhookOnly, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("second instance claimed %d leased messages, want 0", len(again))
+	}
+}
+
+// Instances claiming at the same time must never get the same message.
+func TestClaimPendingMessagesConcurrently(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	const total = 20
+	for i := 0; i < total; i++ {
+		createMessage(t, repo, entity.Message{})
+	}
+
+	var mu sync.Mutex
+	counts := make(map[uuid.UUID]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		owner := "instance-" + string(rune('a'+i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				claimed, err := repo.ClaimPendingMessages(owner, "", webhookOnly, 3, time.Minute)
+				if err != nil {
+					t.Errorf("ClaimPendingMessages(%s) error = %v", owner, err)
+					return
+				}
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, message := range claimed {
+					counts[message.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(counts) != total {
+		t.Errorf("claimed %d distinct messages, want %d", len(counts), total)
+	}
+	for id, count := range counts {
+		if count != 1 {
+			t.Errorf("message %s was claimed %d times", id, count)
+		}
+	}
+}
+
+func TestClaimAfterLeaseExpiry(t *testing.T) {
+	repo, db := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	expireLease(t, db, message.ID)
+
+	claimed, err := repo.ClaimPendingMessages("instance-b", "", webhookOnly, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != message.ID || claimed[0].ClaimedBy != "instance-b" {
+		t.Fatalf("claimed %v after the lease expired, want the message for instance-b", claimed)
+	}
+
+	// The first owner finishes late and must not overwrite the new claim.
+	err = repo.ScheduleRetry(message.ID, "instance-a", "timeout", time.Now().Add(time.Minute))
+	if !errors.Is(err, ErrClaimLost) {
+		t.Errorf("ScheduleRetry() by the old owner error = %v, want ErrClaimLost", err)
+	}
+	if err := repo.MarkSent(message.ID, "instance-a", "sms", "provider-a", time.Now()); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("MarkSent() by the old owner error = %v, want ErrClaimLost", err)
+	}
+
+	if err := repo.MarkSent(message.ID, "instance-b", "sms", "provider-b", time.Now()); err != nil {
+		t.Fatalf("MarkSent() by the new owner error = %v", err)
+	}
+	if err := repo.MarkSent(message.ID, "instance-a", "sms", "provider-a", time.Now()); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("MarkSent() of a sent message error = %v, want ErrMessageNotPending", err)
+	}
+
+	stored := getMessage(t, repo, message.ID)
+	if stored.Status != entity.StatusSent || stored.MessageID != "provider-b" || stored.AttemptCount != 1 {
+		t.Errorf("stored message = %s %q after %d attempts, want sent provider-b after 1",
+			stored.Status, stored.MessageID, stored.AttemptCount)
+	}
+}
+
+func TestUpdateClaimedRequiresOwner(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+
+	nextAttemptAt := time.Now().Add(time.Minute)
+	if err := repo.DeferClaim(message.ID, "instance-b", "rate limited", nextAttemptAt); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("DeferClaim() by another instance error = %v, want ErrClaimLost", err)
+	}
+	if err := repo.MarkFailed(message.ID, "instance-b", "rejected"); !errors.Is(err, ErrClaimLost) {
+		t.Errorf("MarkFailed() by another instance error = %v, want ErrClaimLost", err)
+	}
+
+	if err := repo.ScheduleRetry(message.ID, "instance-a", "timeout", nextAttemptAt); err != nil {
+		t.Fatalf("ScheduleRetry() error = %v", err)
+	}
+	stored := getMessage(t, repo, message.ID)
+	if stored.Status != entity.StatusPending || stored.AttemptCount != 1 || stored.LastError != "timeout" {
+		t.Errorf("stored message = %s after %d attempts with %q, want pending after 1 with timeout",
+			stored.Status, stored.AttemptCount, stored.LastError)
+	}
+	if stored.ClaimedBy != "" || stored.ClaimedUntil != nil {
+		t.Errorf("stored claim = %q until %v, want it released", stored.ClaimedBy, stored.ClaimedUntil)
+	}
+	if stored.NextAttemptAt == nil || stored.NextAttemptAt.Before(time.Now()) {
+		t.Errorf("next attempt at %v, want %v", stored.NextAttemptAt, nextAttemptAt)
+	}
+
+	claimed, err := repo.ClaimPendingMessages("instance-b", "", webhookOnly, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("claimed a message before its next attempt")
+	}
+}
+
+func TestUpdatePending(t *testing.T) {
+	repo, db := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+
+	updated, err := repo.UpdatePending(message.ID, map[string]interface{}{"content": "Updated"})
+	if err != nil {
+		t.Fatalf("UpdatePending() error = %v", err)
+	}
+	if updated.Content != "Updated" {
+		t.Errorf("Content = %q, want Updated", updated.Content)
+	}
+
+	// A claimed message may be in flight, so it cannot be changed until its
+	// lease expires.
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if _, err := repo.UpdatePending(message.ID, map[string]interface{}{"content": "Too late"}); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("UpdatePending() of a claimed message error = %v, want ErrMessageNotPending", err)
+	}
+	if _, err := repo.Cancel(message.ID); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("Cancel() of a claimed message error = %v, want ErrMessageNotPending", err)
+	}
+
+	expireLease(t, db, message.ID)
+	if _, err := repo.UpdatePending(message.ID, map[string]interface{}{"content": "After expiry"}); err != nil {
+		t.Errorf("UpdatePending() after the lease expired error = %v", err)
+	}
+
+	if _, err := repo.UpdatePending(uuid.New(), map[string]interface{}{"content": "Missing"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("UpdatePending() of an unknown message error = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+	sent := createMessage(t, repo, entity.Message{Status: entity.StatusSent, MessageID: "provider-1"})
+
+	cancelled, err := repo.Cancel(message.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if cancelled.Status != entity.StatusCancelled {
+		t.Errorf("Status = %q, want %q", cancelled.Status, entity.StatusCancelled)
+	}
+
+	if _, err := repo.Cancel(message.ID); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("Cancel() of a cancelled message error = %v, want ErrMessageNotPending", err)
+	}
+	if _, err := repo.UpdatePending(message.ID, map[string]interface{}{"content": "Updated"}); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("UpdatePending() of a cancelled message error = %v, want ErrMessageNotPending", err)
+	}
+	if _, err := repo.Cancel(sent.ID); !errors.Is(err, ErrMessageNotPending) {
+		t.Errorf("Cancel() of a sent message error = %v, want ErrMessageNotPending", err)
+	}
+
+	claimed, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Errorf("claimed %d messages after cancelling the only pending one, want 0", len(claimed))
+	}
+}
+
+func TestEventsRecordStateChanges(t *testing.T) {
+	repo, db := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+
+	if _, err := repo.UpdatePending(message.ID, map[string]interface{}{"content": "Updated"}); err != nil {
+		t.Fatalf("UpdatePending() error = %v", err)
+	}
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if err := repo.ScheduleRetry(message.ID, "instance-a", "timeout", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("ScheduleRetry() error = %v", err)
+	}
+	err := db.Model(&entity.Message{}).Where("id = ?", message.ID).
+		Update("next_attempt_at", gorm.Expr("NOW() - INTERVAL '1 second'")).Error
+	if err != nil {
+		t.Fatalf("moving next attempt: %v", err)
+	}
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+	if err := repo.MarkSent(message.ID, "instance-a", "sms", "provider-1", time.Now()); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+	if _, err := repo.UpdateDelivery([]string{"sms"}, "provider-1", entity.StatusDelivered, time.Now(), ""); err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+
+	events, err := repo.GetEvents(message.ID)
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	want := []struct {
+		eventType string
+		status    string
+		attempts  int
+		err       string
+	}{
+		{entity.EventCreated, entity.StatusPending, 0, ""},
+		{entity.EventUpdated, entity.StatusPending, 0, ""},
+		{entity.EventClaimed, entity.StatusPending, 0, ""},
+		{entity.EventRetryScheduled, entity.StatusPending, 1, "timeout"},
+		{entity.EventClaimed, entity.StatusPending, 1, ""},
+		{entity.EventSent, entity.StatusSent, 2, ""},
+		{entity.EventDelivered, entity.StatusDelivered, 2, ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, event := range events {
+		w := want[i]
+		if event.Type != w.eventType || event.Status != w.status || event.AttemptCount != w.attempts || event.Error != w.err {
+			t.Errorf("event %d = %s %s after %d attempts with %q, want %s %s after %d with %q",
+				i, event.Type, event.Status, event.AttemptCount, event.Error, w.eventType, w.status, w.attempts, w.err)
+		}
+	}
+}
+
+// A change that is refused must not leave an event behind, since the event
+// is written in the transaction of the change.
+func TestRefusedChangeRecordsNoEvent(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{})
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+
+	if err := repo.MarkFailed(message.ID, "instance-b", "rejected"); !errors.Is(err, ErrClaimLost) {
+		t.Fatalf("MarkFailed() by another instance error = %v, want ErrClaimLost", err)
+	}
+	if _, err := repo.Cancel(message.ID); !errors.Is(err, ErrMessageNotPending) {
+		t.Fatalf("Cancel() of a claimed message error = %v, want ErrMessageNotPending", err)
+	}
+
+	events, err := repo.GetEvents(message.ID)
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	if len(events) != 2 || events[1].Type != entity.EventClaimed {
+		t.Errorf("events = %+v, want only created and claimed", events)
+	}
+}
+
+func TestMarkSent(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	message := createMessage(t, repo, entity.Message{AttemptCount: 1, LastError: "timeout"})
+	if _, err := repo.ClaimPendingMessages("instance-a", "", webhookOnly, 1, time.Minute); err != nil {
+		t.Fatalf("ClaimPendingMessages() error = %v", err)
+	}
+
+	sentAt := time.Now().Truncate(time.Second)
+	if err := repo.MarkSent(message.ID, "instance-a", "sms", "provider-1", sentAt); err != nil {
+		t.Fatalf("MarkSent() error = %v", err)
+	}
+
+	stored := getMessage(t, repo, message.ID)
+	if stored.Status != entity.StatusSent || stored.MessageID != "provider-1" || stored.Provider != "sms" {
+		t.Errorf("stored message = %s %q from %q, want sent provider-1 from sms", stored.Status, stored.MessageID, stored.Provider)
+	}
+	if !stored.SentAt.Equal(sentAt) {
+		t.Errorf("SentAt = %v, want %v", stored.SentAt, sentAt)
+	}
+	if stored.AttemptCount != 2 || stored.LastError != "" || stored.NextAttemptAt != nil {
+		t.Errorf("attempts = %d, last error %q, next attempt %v, want 2, none, none",
+			stored.AttemptCount, stored.LastError, stored.NextAttemptAt)
+	}
+	if stored.ClaimedBy != "" || stored.ClaimedUntil != nil {
+		t.Errorf("stored claim = %q until %v, want it released", stored.ClaimedBy, stored.ClaimedUntil)
+	}
+}
+
+func TestRecoverInterruptedSends(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	leasedUntil := time.Now().Add(time.Minute)
+	halfSent := createMessage(t, repo, entity.Message{MessageID: "provider-1", ClaimedBy: "instance-b", ClaimedUntil: &leasedUntil})
+	owned := createMessage(t, repo, entity.Message{ClaimedBy: "instance-a", ClaimedUntil: &leasedUntil})
+	other := createMessage(t, repo, entity.Message{ClaimedBy: "instance-b", ClaimedUntil: &leasedUntil})
+
+	result, err := repo.RecoverInterruptedSends("instance-a")
+	if err != nil {
+		t.Fatalf("RecoverInterruptedSends() error = %v", err)
+	}
+	if result.Completed != 1 || result.Released != 1 {
+		t.Errorf("RecoverInterruptedSends() = %+v, want 1 completed and 1 released", result)
+	}
+
+	stored := getMessage(t, repo, halfSent.ID)
+	if stored.Status != entity.StatusSent || stored.AttemptCount != 1 || stored.SentAt.IsZero() || stored.ClaimedBy != "" {
+		t.Errorf("half-sent message = %s after %d attempts, sent at %v, claimed by %q, want sent after 1 and released",
+			stored.Status, stored.AttemptCount, stored.SentAt, stored.ClaimedBy)
+	}
+
+	stored = getMessage(t, repo, owned.ID)
+	if stored.Status != entity.StatusPending || stored.ClaimedBy != "" || stored.ClaimedUntil != nil {
+		t.Errorf("message leased by this instance = %s claimed by %q until %v, want pending and released",
+			stored.Status, stored.ClaimedBy, stored.ClaimedUntil)
+	}
+
+	stored = getMessage(t, repo, other.ID)
+	if stored.ClaimedBy != "instance-b" || stored.ClaimedUntil == nil {
+		t.Errorf("message leased by another instance claimed by %q until %v, want its lease kept",
+			stored.ClaimedBy, stored.ClaimedUntil)
+	}
+
+	for id, wantType := range map[uuid.UUID]string{halfSent.ID: entity.EventSent, owned.ID: entity.EventReleased} {
+		events, err := repo.GetEvents(id)
+		if err != nil {
+			t.Fatalf("GetEvents() error = %v", err)
+		}
+		if last := events[len(events)-1]; last.Type != wantType {
+			t.Errorf("last event of %s = %s, want %s", id, last.Type, wantType)
+		}
+	}
+
+	// A second run finds nothing left to reconcile.
+	result, err = repo.RecoverInterruptedSends("instance-a")
+	if err != nil {
+		t.Fatalf("RecoverInterruptedSends() error = %v", err)
+	}
+	if result.Completed != 0 || result.Released != 0 {
+		t.Errorf("second RecoverInterruptedSends() = %+v, want nothing", result)
+	}
+}
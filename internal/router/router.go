@@ -8,8 +8,11 @@ import (
 )
 
 type Config struct {
-	MessageHandler handler.MessageHandler
-	HealthConfig   health.Config
+	MessageHandler   handler.MessageHandler
+	ImportHandler    handler.ImportHandler
+	CallbackHandler  handler.CallbackHandler
+	RateLimitHandler handler.RateLimitHandler
+	HealthConfig     health.Config
 }
 
 func SetupRoutes(e *echo.Echo, config Config) {
@@ -22,4 +25,9 @@ func SetupRoutes(e *echo.Echo, config Config) {
 func registerV1Routes(e *echo.Echo, v1 *echo.Group, config Config) {
 	messages := v1.Group("/messages")
 	config.MessageHandler.RegisterRoutes(messages)
+	config.ImportHandler.RegisterRoutes(messages)
+	config.RateLimitHandler.RegisterRoutes(messages)
+
+	callbacks := v1.Group("/callbacks")
+	config.CallbackHandler.RegisterRoutes(callbacks)
 }
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/repository"
+	"auto-message-sender/pkg/logger"
+)
+
+// RecordDeliveryReceipt moves a sent message to delivered or undelivered
+// according to the provider's delivery report.
+func (s *messageService) RecordDeliveryReceipt(ctx context.Context, req *request.DeliveryReceiptRequest) (*entity.Message, error) {
+	log := logger.WithFields(logrus.Fields{
+		"provider":          req.Provider,
+		"providerMessageID": req.MessageID,
+		"status":            req.Status,
+	})
+
+	providers := []string{req.Provider}
+	if req.Provider == config.DefaultWebhookProvider {
+		// Messages sent before the provider was recorded have none.
+		providers = append(providers, "")
+	}
+
+	message, err := s.repo.UpdateDelivery(providers, req.MessageID, req.Status, req.ReportedAt(), req.Error)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		log.Warn("Delivery receipt for unknown message")
+		return nil, ErrMessageNotFound
+	case errors.Is(err, repository.ErrMessageNotSent):
+		log.Warn("Delivery receipt for a message that was not sent")
+		return nil, ErrMessageNotSent
+	case err != nil:
+		log.WithError(err).Error("Failed to record delivery receipt")
+		return nil, err
+	}
+
+	if message.Status != req.Status {
+		log.WithField("currentStatus", message.Status).Info("Ignored delivery receipt older than the recorded one")
+	} else {
+		log.WithField("messageID", message.ID.String()).Info("Delivery receipt recorded")
+	}
+	return message, nil
+}
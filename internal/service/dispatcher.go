@@ -0,0 +1,106 @@
+package service
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/pkg/logger"
+)
+
+type DispatcherSettings struct {
+	Interval   time.Duration
+	BatchSize  int
+	MaxPerTick int
+	Workers    int
+	Running    bool
+	Pool       WorkerPoolStats
+}
+
+func newDispatcherSettings() DispatcherSettings {
+	return DispatcherSettings{
+		Interval:   config.AppSettings.Dispatcher.Interval,
+		BatchSize:  config.AppSettings.Dispatcher.BatchSize,
+		MaxPerTick: config.AppSettings.Dispatcher.MaxPerTick,
+		Workers:    config.AppSettings.Dispatcher.Workers,
+	}
+}
+
+func (s *messageService) GetDispatcherSettings() DispatcherSettings {
+	settings := s.currentSettings()
+
+	s.runningMutex.Lock()
+	settings.Running = s.isRunning
+	s.runningMutex.Unlock()
+
+	if pool := s.currentPool(); pool != nil {
+		settings.Pool = pool.Stats()
+	}
+
+	return settings
+}
+
+// currentSettings is used by the processing loop, which must not take
+// runningMutex because StopSending holds it while waiting for the loop to exit.
+func (s *messageService) currentSettings() DispatcherSettings {
+	s.settingsMutex.RLock()
+	defer s.settingsMutex.RUnlock()
+	return s.settings
+}
+
+func (s *messageService) UpdateDispatcherSettings(req *request.UpdateDispatcherRequest) (DispatcherSettings, error) {
+	s.settingsMutex.Lock()
+	updated := s.settings
+	if req.Interval != nil {
+		interval, err := time.ParseDuration(*req.Interval)
+		if err != nil {
+			s.settingsMutex.Unlock()
+			return DispatcherSettings{}, err
+		}
+		updated.Interval = interval
+	}
+	if req.BatchSize != nil {
+		updated.BatchSize = *req.BatchSize
+	}
+	if req.MaxPerTick != nil {
+		updated.MaxPerTick = *req.MaxPerTick
+	}
+	if req.Workers != nil {
+		updated.Workers = *req.Workers
+	}
+	s.settings = updated
+	s.settingsMutex.Unlock()
+
+	logger.WithFields(logrus.Fields{
+		"interval":   updated.Interval.String(),
+		"batchSize":  updated.BatchSize,
+		"maxPerTick": updated.MaxPerTick,
+		"workers":    updated.Workers,
+	}).Info("Dispatcher settings updated")
+
+	select {
+	case s.settingsChanged <- struct{}{}:
+	default:
+	}
+
+	return s.GetDispatcherSettings(), nil
+}
+
+func (s *messageService) currentPool() *workerPool {
+	s.poolMutex.RLock()
+	defer s.poolMutex.RUnlock()
+	return s.pool
+}
+
+func (s *messageService) replacePool(pool *workerPool) {
+	s.poolMutex.Lock()
+	previous := s.pool
+	s.pool = pool
+	s.poolMutex.Unlock()
+
+	if previous != nil {
+		previous.Stop()
+	}
+}
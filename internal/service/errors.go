@@ -0,0 +1,14 @@
+package service
+
+import "errors"
+
+var (
+	ErrMessageNotFound          = errors.New("message not found")
+	ErrMessageNotPending        = errors.New("message is no longer pending or is already being sent")
+	ErrIdempotencyKeyConflict   = errors.New("idempotency key was already used with a different request body")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrImportNotFound           = errors.New("import not found")
+	ErrInvalidCursor            = errors.New("cursor is invalid or expired")
+	ErrMessageNotSent           = errors.New("message has not been sent")
+	ErrInvalidUpdate            = errors.New("update is not valid for the message's channel")
+)
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/exporter"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/pkg/logger"
+)
+
+// ExportMessages streams every message matching filter to w in the given
+// format and returns the number of exported messages.
+func (s *messageService) ExportMessages(ctx context.Context, filter *request.MessageFilterRequest, format string, w io.Writer) (int, error) {
+	writer, err := exporter.NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	log := logger.WithFields(logrus.Fields{
+		"format":   format,
+		"statuses": filter.Statuses(),
+		"toPrefix": filter.ToPrefix,
+		"sort":     filter.Sort,
+	})
+	log.Info("Starting message export")
+	startedAt := time.Now()
+
+	count := 0
+	err = s.repo.StreamMessages(ctx, filter, func(message *entity.Message) error {
+		count++
+		return writer.Write(message)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		log.WithError(err).WithField("exported", count).Error("Message export failed")
+		return count, err
+	}
+
+	log.WithFields(logrus.Fields{
+		"exported": count,
+		"duration": time.Since(startedAt).String(),
+	}).Info("Message export completed")
+	return count, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/pkg/logger"
+)
+
+// CreateMessageIdempotent creates a message at most once per idempotency key.
+// A repeated request with the same body returns the original message and
+// replayed set to true; the same key with a different body is rejected. The
+// key is reserved for the reservation timeout while the message is created
+// and kept for the retention period once it has been.
+func (s *messageService) CreateMessageIdempotent(ctx context.Context, key string, req *request.SendMessageRequest) (*entity.Message, bool, error) {
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	record, err := s.redisSvc.ReserveIdempotencyKey(ctx, key, fingerprint, s.idempotencyTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if record != nil {
+		fields := logrus.Fields{
+			"idempotencyKey": key,
+			"messageID":      record.MessageID,
+		}
+		if record.Fingerprint != fingerprint {
+			logger.WithFields(fields).Warn("Idempotency key reused with a different request body")
+			return nil, false, ErrIdempotencyKeyConflict
+		}
+		if record.MessageID == "" {
+			return nil, false, ErrIdempotencyKeyInProgress
+		}
+
+		messageID, err := uuid.Parse(record.MessageID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		logger.WithFields(fields).Info("Replaying message creation for idempotency key")
+		message, err := s.repo.GetByID(messageID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrMessageNotFound
+		}
+		if err != nil {
+			logger.WithFields(fields).WithError(err).Error("Failed to load message for idempotency replay")
+			return nil, false, err
+		}
+		return message, true, nil
+	}
+
+	message, err := s.CreateMessage(ctx, req)
+	if err != nil {
+		if releaseErr := s.redisSvc.ReleaseIdempotencyKey(ctx, key); releaseErr != nil {
+			logger.WithFields(logrus.Fields{
+				"idempotencyKey": key,
+				"error":          releaseErr.Error(),
+			}).Warn("Failed to release idempotency key after a failed request")
+		}
+		return nil, false, err
+	}
+
+	err = s.redisSvc.CompleteIdempotencyKey(ctx, key, IdempotencyRecord{
+		Fingerprint: fingerprint,
+		MessageID:   message.ID.String(),
+	}, s.idempotencyRetention)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"idempotencyKey": key,
+			"messageID":      message.ID.String(),
+			"error":          err.Error(),
+		}).Warn("Failed to store idempotency record, retries with this key will be rejected until it expires")
+	}
+
+	return message, false, nil
+}
+
+func requestFingerprint(req *request.SendMessageRequest) (string, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/config"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/importer"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/repository"
+	"auto-message-sender/internal/validator"
+	"auto-message-sender/pkg/logger"
+)
+
+const (
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+
+	importErrorFlushSize = 500
+)
+
+type ImportService interface {
+	Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportSummary, error)
+	GetSummary(ctx context.Context, importID string) (*ImportSummary, error)
+	WriteErrorReport(ctx context.Context, importID string, w io.Writer) error
+}
+
+type ImportOptions struct {
+	Format          string
+	ToColumn        string
+	ContentColumn   string
+	ContentTemplate string
+	SubjectColumn   string
+	HTMLColumn      string
+	Priority        string
+	Channel         string
+}
+
+type ImportSummary struct {
+	ImportID   string    `json:"import_id"`
+	Format     string    `json:"format"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Total      int       `json:"total"`
+	Accepted   int       `json:"accepted"`
+	Rejected   int       `json:"rejected"`
+	Duplicates int       `json:"duplicates"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type importService struct {
+	repo            repository.MessageRepository
+	redisSvc        RedisService
+	insertBatchSize int
+	reportRetention time.Duration
+	dedupWindow     int
+}
+
+func NewImportService(repo repository.MessageRepository, redisSvc RedisService) ImportService {
+	return &importService{
+		repo:            repo,
+		redisSvc:        redisSvc,
+		insertBatchSize: config.AppSettings.Batch.InsertSize,
+		reportRetention: config.AppSettings.Import.ReportRetention,
+		dedupWindow:     config.AppSettings.Import.DedupWindow,
+	}
+}
+
+// importRun holds the state of a single import. Only hashes of the last
+// dedupWindow accepted rows are kept to detect duplicates, so memory stays
+// bounded for files of any size; rows themselves are flushed in batches.
+type importRun struct {
+	svc       *importService
+	ctx       context.Context
+	opts      ImportOptions
+	tmpl      *template.Template
+	summary   *ImportSummary
+	seen      map[[sha256.Size]byte]struct{}
+	order     [][sha256.Size]byte
+	next      int
+	pending   []entity.Message
+	errorRows []string
+}
+
+func (s *importService) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportSummary, error) {
+	run := &importRun{
+		svc:  s,
+		ctx:  ctx,
+		opts: opts,
+		summary: &ImportSummary{
+			ImportID:  uuid.New().String(),
+			Format:    opts.Format,
+			StartedAt: time.Now(),
+		},
+		seen:    make(map[[sha256.Size]byte]struct{}),
+		order:   make([][sha256.Size]byte, 0, min(s.dedupWindow, s.insertBatchSize)),
+		pending: make([]entity.Message, 0, s.insertBatchSize),
+	}
+
+	if opts.ContentTemplate != "" {
+		tmpl, err := template.New("content").Option("missingkey=error").Parse(opts.ContentTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content template: %w", err)
+		}
+		run.tmpl = tmpl
+	}
+
+	reader, err := importer.NewRowReader(opts.Format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"importID": run.summary.ImportID,
+		"format":   opts.Format,
+	}).Info("Starting message import")
+
+	err = run.consume(reader)
+	if err == nil {
+		err = run.flushMessages()
+	}
+	if flushErr := run.flushErrors(); err == nil {
+		err = flushErr
+	}
+
+	run.summary.FinishedAt = time.Now()
+	run.summary.Status = ImportStatusCompleted
+	if err != nil {
+		run.summary.Status = ImportStatusFailed
+		run.summary.Error = err.Error()
+	}
+
+	if saveErr := s.redisSvc.SaveImportSummary(ctx, *run.summary, s.reportRetention); saveErr != nil {
+		logger.WithFields(logrus.Fields{
+			"importID": run.summary.ImportID,
+			"error":    saveErr.Error(),
+		}).Warn("Failed to store import summary")
+	}
+
+	fields := logrus.Fields{
+		"importID":   run.summary.ImportID,
+		"total":      run.summary.Total,
+		"accepted":   run.summary.Accepted,
+		"rejected":   run.summary.Rejected,
+		"duplicates": run.summary.Duplicates,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.WithFields(fields).Error("Message import failed")
+		return run.summary, err
+	}
+
+	logger.WithFields(fields).Info("Message import completed")
+	return run.summary, nil
+}
+
+func (run *importRun) consume(reader importer.RowReader) error {
+	for {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+
+		row, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var rowErr *importer.RowError
+			if !errors.As(err, &rowErr) {
+				return err
+			}
+			run.summary.Total++
+			run.summary.Rejected++
+			if err := run.reject(rowErr.Line, rowErr.Err.Error(), rowErr.Raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		run.summary.Total++
+		if err := run.accept(row); err != nil {
+			return err
+		}
+	}
+}
+
+func (run *importRun) accept(row *importer.Row) error {
+	req, err := run.toRequest(row)
+	if err == nil {
+		err = validator.ValidateRecipient(req.DeliveryChannel(), req.To)
+	}
+	if err == nil {
+		err = validator.ValidateMessageBody(req.DeliveryChannel(), req.Subject, req.Content, req.HTML)
+	}
+	if err != nil {
+		run.summary.Rejected++
+		return run.reject(row.Line, err.Error(), row.Raw)
+	}
+
+	key := dedupKey(req)
+	if _, ok := run.seen[key]; ok {
+		run.summary.Duplicates++
+		return run.reject(row.Line, "duplicate row", row.Raw)
+	}
+	run.remember(key)
+
+	run.pending = append(run.pending, newPendingMessage(req))
+	if len(run.pending) >= run.svc.insertBatchSize {
+		return run.flushMessages()
+	}
+	return nil
+}
+
+// dedupKey hashes every field that goes into the message, so rows that only
+// differ in, say, their subject are not taken for duplicates.
+func dedupKey(req *request.SendMessageRequest) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.Join([]string{
+		req.To, req.Content, req.Subject, req.HTML, req.DeliveryChannel(), req.Priority,
+	}, "\x00")))
+}
+
+// remember records the hash of an accepted row, forgetting the oldest one
+// once the window is full.
+func (run *importRun) remember(key [sha256.Size]byte) {
+	if len(run.order) < run.svc.dedupWindow {
+		run.order = append(run.order, key)
+	} else {
+		delete(run.seen, run.order[run.next])
+		run.order[run.next] = key
+		run.next = (run.next + 1) % len(run.order)
+	}
+	run.seen[key] = struct{}{}
+}
+
+func (run *importRun) toRequest(row *importer.Row) (*request.SendMessageRequest, error) {
+	to, ok := row.Fields[run.opts.ToColumn]
+	if !ok {
+		return nil, fmt.Errorf("column %q is missing", run.opts.ToColumn)
+	}
+
+	var content string
+	if run.tmpl != nil {
+		var buf bytes.Buffer
+		if err := run.tmpl.Execute(&buf, row.Fields); err != nil {
+			return nil, fmt.Errorf("failed to render content template: %w", err)
+		}
+		content = buf.String()
+	} else {
+		content, ok = row.Fields[run.opts.ContentColumn]
+		if !ok {
+			return nil, fmt.Errorf("column %q is missing", run.opts.ContentColumn)
+		}
+	}
+
+	req := &request.SendMessageRequest{
+		To:       strings.TrimSpace(to),
+		Content:  strings.TrimSpace(content),
+		Priority: run.opts.Priority,
+		Channel:  run.opts.Channel,
+	}
+	if run.opts.SubjectColumn != "" {
+		req.Subject = strings.TrimSpace(row.Fields[run.opts.SubjectColumn])
+	}
+	if run.opts.HTMLColumn != "" {
+		req.HTML = row.Fields[run.opts.HTMLColumn]
+	}
+	return req, nil
+}
+
+func (run *importRun) reject(line int, reason, raw string) error {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write([]string{strconv.Itoa(line), reason, raw}); err != nil {
+		return err
+	}
+	writer.Flush()
+
+	run.errorRows = append(run.errorRows, buf.String())
+	if len(run.errorRows) >= importErrorFlushSize {
+		return run.flushErrors()
+	}
+	return nil
+}
+
+func (run *importRun) flushMessages() error {
+	if len(run.pending) == 0 {
+		return nil
+	}
+
+	if err := run.svc.repo.CreateBatch(run.pending, run.svc.insertBatchSize); err != nil {
+		return fmt.Errorf("failed to insert imported messages: %w", err)
+	}
+
+	run.summary.Accepted += len(run.pending)
+	run.pending = run.pending[:0]
+	return nil
+}
+
+func (run *importRun) flushErrors() error {
+	if len(run.errorRows) == 0 {
+		return nil
+	}
+
+	err := run.svc.redisSvc.AppendImportErrors(run.ctx, run.summary.ImportID, run.errorRows, run.svc.reportRetention)
+	if err != nil {
+		return fmt.Errorf("failed to store import error report: %w", err)
+	}
+
+	run.errorRows = run.errorRows[:0]
+	return nil
+}
+
+func (s *importService) GetSummary(ctx context.Context, importID string) (*ImportSummary, error) {
+	summary, err := s.redisSvc.GetImportSummary(ctx, importID)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	return summary, nil
+}
+
+// WriteErrorReport writes the rejected and duplicate rows of an import as CSV
+// with the columns line, reason and row, reading the report page by page.
+func (s *importService) WriteErrorReport(ctx context.Context, importID string, w io.Writer) error {
+	if _, err := s.GetSummary(ctx, importID); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, "line,reason,row\n"); err != nil {
+		return err
+	}
+
+	const pageSize = 1000
+	for offset := int64(0); ; offset += pageSize {
+		rows, err := s.redisSvc.GetImportErrors(ctx, importID, offset, pageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if _, err := io.WriteString(w, row); err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < pageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/model/request"
+)
+
+func TestDedupKey(t *testing.T) {
+	base := request.SendMessageRequest{
+		To:       "user@example.com",
+		Content:  "Hello",
+		Subject:  "Hi",
+		HTML:     "<p>Hello</p>",
+		Channel:  entity.ChannelEmail,
+		Priority: entity.PriorityNormal,
+	}
+
+	tests := []struct {
+		name   string
+		change func(req *request.SendMessageRequest)
+	}{
+		{"recipient", func(req *request.SendMessageRequest) { req.To = "other@example.com" }},
+		{"content", func(req *request.SendMessageRequest) { req.Content = "Bye" }},
+		{"subject", func(req *request.SendMessageRequest) { req.Subject = "Hello" }},
+		{"html", func(req *request.SendMessageRequest) { req.HTML = "" }},
+		{"channel", func(req *request.SendMessageRequest) { req.Channel = entity.ChannelWebhook }},
+		{"priority", func(req *request.SendMessageRequest) { req.Priority = entity.PriorityBulk }},
+		{"field boundary", func(req *request.SendMessageRequest) { req.Content, req.Subject = "HelloHi", "" }},
+	}
+
+	same := base
+	if dedupKey(&base) != dedupKey(&same) {
+		t.Fatal("dedupKey() differs for identical rows")
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			if dedupKey(&base) == dedupKey(&changed) {
+				t.Fatalf("dedupKey() ignores a change of the %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"sync"
+
+	"auto-message-sender/internal/entity"
+)
+
+// laneScheduler splits dispatch capacity between the priority lanes using
+// smooth weighted round-robin. Every lane keeps accumulating credit until it
+// is picked, so bulk traffic is served at its weighted share even when the
+// higher lanes are never empty. The weights are validated when the
+// configuration is loaded, so every lane has a positive one.
+type laneScheduler struct {
+	mu      sync.Mutex
+	weights map[string]int
+	current map[string]int
+}
+
+func newLaneScheduler(weights map[string]int) *laneScheduler {
+	return &laneScheduler{
+		weights: weights,
+		current: make(map[string]int, len(weights)),
+	}
+}
+
+func (l *laneScheduler) allocate(capacity int) map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total := 0
+	for _, weight := range l.weights {
+		total += weight
+	}
+
+	allocation := make(map[string]int, len(l.weights))
+	for i := 0; i < capacity; i++ {
+		picked := ""
+		for _, priority := range entity.Priorities {
+			weight, ok := l.weights[priority]
+			if !ok {
+				continue
+			}
+			l.current[priority] += weight
+			if picked == "" || l.current[priority] > l.current[picked] {
+				picked = priority
+			}
+		}
+		l.current[picked] -= total
+		allocation[picked]++
+	}
+
+	return allocation
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"auto-message-sender/internal/entity"
+)
+
+func TestLaneSchedulerAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  map[string]int
+		capacity int
+		want     map[string]int
+	}{
+		{
+			name:     "weighted shares",
+			weights:  map[string]int{entity.PriorityHigh: 6, entity.PriorityNormal: 3, entity.PriorityBulk: 1},
+			capacity: 100,
+			want:     map[string]int{entity.PriorityHigh: 60, entity.PriorityNormal: 30, entity.PriorityBulk: 10},
+		},
+		{
+			name:     "equal weights",
+			weights:  map[string]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityBulk: 1},
+			capacity: 9,
+			want:     map[string]int{entity.PriorityHigh: 3, entity.PriorityNormal: 3, entity.PriorityBulk: 3},
+		},
+		{
+			name:     "capacity below the sum of the weights",
+			weights:  map[string]int{entity.PriorityHigh: 6, entity.PriorityNormal: 3, entity.PriorityBulk: 1},
+			capacity: 3,
+			want:     map[string]int{entity.PriorityHigh: 2, entity.PriorityNormal: 1},
+		},
+		{
+			name:     "no capacity",
+			weights:  map[string]int{entity.PriorityHigh: 1, entity.PriorityNormal: 1, entity.PriorityBulk: 1},
+			capacity: 0,
+			want:     map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newLaneScheduler(tt.weights).allocate(tt.capacity)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("allocate(%d) = %v, want %v", tt.capacity, got, tt.want)
+			}
+		})
+	}
+}
+
+// The lowest lane must get its share even when every tick is smaller than
+// the sum of the weights, since its credit carries over between ticks.
+func TestLaneSchedulerCarriesCreditAcrossTicks(t *testing.T) {
+	scheduler := newLaneScheduler(map[string]int{
+		entity.PriorityHigh:   6,
+		entity.PriorityNormal: 3,
+		entity.PriorityBulk:   1,
+	})
+
+	total := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		for priority, count := range scheduler.allocate(2) {
+			total[priority] += count
+		}
+	}
+
+	want := map[string]int{entity.PriorityHigh: 60, entity.PriorityNormal: 30, entity.PriorityBulk: 10}
+	if !reflect.DeepEqual(total, want) {
+		t.Fatalf("allocated %v over 50 ticks, want %v", total, want)
+	}
+}
@@ -2,13 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 
+	"auto-message-sender/internal/channel"
 	"auto-message-sender/internal/client"
+	"auto-message-sender/internal/config"
 	"auto-message-sender/internal/entity"
 	"auto-message-sender/internal/model/request"
 	"auto-message-sender/internal/repository"
@@ -17,28 +25,60 @@ import (
 
 type MessageService interface {
 	StartSending(ctx context.Context) error
+	RecoverInterruptedSends(ctx context.Context) error
 	StopSending() error
-	GetMessages(filter *request.MessageFilterRequest) ([]entity.Message, error)
-	CreateMessage(ctx context.Context, to, content string) (*entity.Message, error)
+	GetMessages(filter *request.MessageFilterRequest) (*repository.MessagePage, error)
+	ExportMessages(ctx context.Context, filter *request.MessageFilterRequest, format string, w io.Writer) (int, error)
+	GetMessage(ctx context.Context, id uuid.UUID) (*MessageDetails, error)
+	GetMessageEvents(ctx context.Context, id uuid.UUID) ([]entity.MessageEvent, error)
+	UpdateMessage(ctx context.Context, id uuid.UUID, req *request.UpdateMessageRequest) (*entity.Message, error)
+	CancelMessage(ctx context.Context, id uuid.UUID) (*entity.Message, error)
+	RecordDeliveryReceipt(ctx context.Context, req *request.DeliveryReceiptRequest) (*entity.Message, error)
+	CreateMessage(ctx context.Context, req *request.SendMessageRequest) (*entity.Message, error)
+	CreateMessageIdempotent(ctx context.Context, key string, req *request.SendMessageRequest) (*entity.Message, bool, error)
+	CreateMessages(ctx context.Context, reqs []*request.SendMessageRequest) ([]entity.Message, error)
+	GetDispatcherSettings() DispatcherSettings
+	UpdateDispatcherSettings(req *request.UpdateDispatcherRequest) (DispatcherSettings, error)
 }
 
 type messageService struct {
-	repo          repository.MessageRepository
-	webhookClient client.WebhookClient
-	redisSvc      RedisService
-	stopChan      chan struct{}
-	wg            sync.WaitGroup
-	isRunning     bool
-	runningMutex  sync.Mutex
+	repo                 repository.MessageRepository
+	channels             *channel.Registry
+	redisSvc             RedisService
+	stopChan             chan struct{}
+	wg                   sync.WaitGroup
+	isRunning            bool
+	runningMutex         sync.Mutex
+	settings             DispatcherSettings
+	settingsMutex        sync.RWMutex
+	settingsChanged      chan struct{}
+	pool                 *workerPool
+	poolMutex            sync.RWMutex
+	instanceID           string
+	leaseDuration        time.Duration
+	retryPolicy          retryPolicy
+	lanes                *laneScheduler
+	idempotencyRetention time.Duration
+	idempotencyTimeout   time.Duration
+	insertBatchSize      int
 }
 
-func NewMessageService(repo repository.MessageRepository, webhookClient client.WebhookClient, redisSvc RedisService) MessageService {
+func NewMessageService(repo repository.MessageRepository, channels *channel.Registry, redisSvc RedisService) MessageService {
 	return &messageService{
-		repo:          repo,
-		webhookClient: webhookClient,
-		redisSvc:      redisSvc,
-		stopChan:      make(chan struct{}),
-		isRunning:     false,
+		repo:                 repo,
+		channels:             channels,
+		redisSvc:             redisSvc,
+		stopChan:             make(chan struct{}),
+		isRunning:            false,
+		settings:             newDispatcherSettings(),
+		settingsChanged:      make(chan struct{}, 1),
+		instanceID:           config.AppSettings.Dispatcher.InstanceID,
+		leaseDuration:        config.AppSettings.Dispatcher.LeaseDuration,
+		retryPolicy:          newRetryPolicy(),
+		lanes:                newLaneScheduler(config.AppSettings.Dispatcher.PriorityWeights),
+		idempotencyRetention: config.AppSettings.Idempotency.Retention,
+		idempotencyTimeout:   config.AppSettings.Idempotency.ReservationTimeout,
+		insertBatchSize:      config.AppSettings.Batch.InsertSize,
 	}
 }
 
@@ -57,7 +97,10 @@ func (s *messageService) StartSending(ctx context.Context) error {
 
 	bgCtx := context.Background()
 
-	logger.Info("Starting automatic message sending service")
+	workers := s.currentSettings().Workers
+	s.replacePool(newWorkerPool(workers))
+
+	logger.WithField("workers", workers).Info("Starting automatic message sending service")
 	go s.processPendingMessages(bgCtx)
 	return nil
 }
@@ -79,41 +122,165 @@ func (s *messageService) StopSending() error {
 	return nil
 }
 
-func (s *messageService) GetMessages(filter *request.MessageFilterRequest) ([]entity.Message, error) {
+func (s *messageService) GetMessages(filter *request.MessageFilterRequest) (*repository.MessagePage, error) {
 	logger.WithFields(logrus.Fields{
-		"status":    filter.Status,
-		"startDate": filter.StartDate,
-		"endDate":   filter.EndDate,
-		"page":      filter.Page,
-		"pageSize":  filter.PageSize,
+		"statuses":   filter.Statuses(),
+		"sort":       filter.Sort,
+		"startDate":  filter.StartDate,
+		"endDate":    filter.EndDate,
+		"page":       filter.Page,
+		"pageSize":   filter.PageSize,
+		"cursorMode": filter.CursorMode(),
 	}).Debug("Retrieving filtered messages")
 
-	messages, err := s.repo.GetMessages(filter)
+	page, err := s.repo.GetMessages(filter)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, ErrInvalidCursor
+		}
 		logger.WithError(err).Error("Failed to retrieve sent messages")
 		return nil, err
 	}
 
-	logger.WithField("count", len(messages)).Debug("Retrieved sent messages")
-	return messages, nil
+	logger.WithFields(logrus.Fields{
+		"count": len(page.Messages),
+		"total": page.Total,
+	}).Debug("Retrieved sent messages")
+	return page, nil
+}
+
+type MessageDetails struct {
+	Message      entity.Message
+	CachedSentAt *time.Time
+}
+
+func (s *messageService) GetMessage(ctx context.Context, id uuid.UUID) (*MessageDetails, error) {
+	message, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		logger.WithFields(logrus.Fields{
+			"messageID": id.String(),
+			"error":     err.Error(),
+		}).Error("Failed to retrieve message")
+		return nil, err
+	}
+
+	details := &MessageDetails{Message: *message}
+	if message.MessageID != "" {
+		sentTime, err := s.redisSvc.GetMessageSentTime(ctx, message.MessageID)
+		if err == nil {
+			details.CachedSentAt = &sentTime
+		}
+	}
+
+	return details, nil
+}
+
+func (s *messageService) GetMessageEvents(ctx context.Context, id uuid.UUID) ([]entity.MessageEvent, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	events, err := s.repo.GetEvents(id)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"messageID": id.String(),
+			"error":     err.Error(),
+		}).Error("Failed to retrieve message events")
+		return nil, err
+	}
+
+	return events, nil
 }
 
-func (s *messageService) CreateMessage(ctx context.Context, to, content string) (*entity.Message, error) {
-	messageID := uuid.New()
+func (s *messageService) UpdateMessage(ctx context.Context, id uuid.UUID, req *request.UpdateMessageRequest) (*entity.Message, error) {
+	// The channel never changes, so checking it before the conditional update
+	// cannot race with another writer. The other body fields are merged from
+	// this read; a concurrent update of the same message is last-write-wins.
+	if req.ChangesBody() {
+		message, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, s.pendingChangeError(id, "update", err)
+		}
+		if err := req.ValidateFor(message); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+		}
+	}
+
+	message, err := s.repo.UpdatePending(id, req.Updates())
+	if err != nil {
+		return nil, s.pendingChangeError(id, "update", err)
+	}
+
+	logger.WithField("messageID", id.String()).Info("Pending message updated")
+	return message, nil
+}
+
+func (s *messageService) CancelMessage(ctx context.Context, id uuid.UUID) (*entity.Message, error) {
+	message, err := s.repo.Cancel(id)
+	if err != nil {
+		return nil, s.pendingChangeError(id, "cancel", err)
+	}
+
+	logger.WithField("messageID", id.String()).Info("Message cancelled")
+	return message, nil
+}
+
+// RecoverInterruptedSends reconciles messages left half-updated by a crash of
+// a previous run. It is meant to be called once at startup, before sending.
+func (s *messageService) RecoverInterruptedSends(ctx context.Context) error {
+	result, err := s.repo.RecoverInterruptedSends(s.instanceID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to recover interrupted sends")
+		return err
+	}
+
 	logger.WithFields(logrus.Fields{
-		"messageID": messageID.String(),
-		"to":        to,
-		"length":    len(content),
-	}).Info("Creating new message")
+		"instanceID": s.instanceID,
+		"completed":  result.Completed,
+		"released":   result.Released,
+	}).Info("Recovery of interrupted sends completed")
+	return nil
+}
 
-	message := &entity.Message{
-		ID:      messageID,
-		To:      to,
-		Content: content,
-		Status:  entity.StatusPending,
+func (s *messageService) pendingChangeError(id uuid.UUID, action string, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrMessageNotFound
+	case errors.Is(err, repository.ErrMessageNotPending):
+		logger.WithFields(logrus.Fields{
+			"messageID": id.String(),
+			"action":    action,
+		}).Info("Rejected change to a message that is not pending")
+		return ErrMessageNotPending
+	default:
+		logger.WithFields(logrus.Fields{
+			"messageID": id.String(),
+			"action":    action,
+			"error":     err.Error(),
+		}).Error("Failed to change pending message")
+		return err
 	}
+}
 
-	err := s.repo.Create(message)
+func (s *messageService) CreateMessage(ctx context.Context, req *request.SendMessageRequest) (*entity.Message, error) {
+	message := newPendingMessage(req)
+	messageID := message.ID
+	logger.WithFields(logrus.Fields{
+		"messageID": messageID.String(),
+		"to":        req.To,
+		"length":    len(req.Content),
+		"sendAt":    req.SendAt,
+		"priority":  message.Priority,
+		"channel":   message.Channel,
+	}).Info("Creating new message")
+
+	err := s.repo.Create(&message)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"messageID": messageID.String(),
@@ -123,16 +290,63 @@ func (s *messageService) CreateMessage(ctx context.Context, to, content string)
 	}
 
 	logger.WithField("messageID", messageID.String()).Info("Message created successfully")
-	return message, nil
+	return &message, nil
+}
+
+func (s *messageService) CreateMessages(ctx context.Context, reqs []*request.SendMessageRequest) ([]entity.Message, error) {
+	messages := make([]entity.Message, len(reqs))
+	for i, req := range reqs {
+		messages[i] = newPendingMessage(req)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"count":     len(messages),
+		"batchSize": s.insertBatchSize,
+	}).Info("Creating messages in batches")
+
+	if err := s.repo.CreateBatch(messages, s.insertBatchSize); err != nil {
+		logger.WithFields(logrus.Fields{
+			"count": len(messages),
+			"error": err.Error(),
+		}).Error("Failed to create messages")
+		return nil, err
+	}
+
+	logger.WithField("count", len(messages)).Info("Messages created successfully")
+	return messages, nil
+}
+
+func newPendingMessage(req *request.SendMessageRequest) entity.Message {
+	priority := req.Priority
+	if priority == "" {
+		priority = entity.PriorityNormal
+	}
+
+	return entity.Message{
+		ID:       uuid.New(),
+		To:       req.To,
+		Content:  req.Content,
+		Subject:  req.Subject,
+		HTML:     req.HTML,
+		Status:   entity.StatusPending,
+		SendAt:   req.ScheduledAt(),
+		Priority: priority,
+		Channel:  req.DeliveryChannel(),
+	}
 }
 
 func (s *messageService) processPendingMessages(ctx context.Context) {
 	defer s.wg.Done()
+	defer func() {
+		s.replacePool(nil)
+		logger.Info("Worker pool drained")
+	}()
 
-	ticker := time.NewTicker(2 * time.Minute)
+	interval := s.currentSettings().Interval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	logger.Info("Message processing routine started")
+	logger.WithField("interval", interval.String()).Info("Message processing routine started")
 
 	for {
 		select {
@@ -142,78 +356,269 @@ func (s *messageService) processPendingMessages(ctx context.Context) {
 		case <-s.stopChan:
 			logger.Info("Message processing stopped via stop channel")
 			return
+		case <-s.settingsChanged:
+			settings := s.currentSettings()
+			if settings.Interval != interval {
+				interval = settings.Interval
+				ticker.Reset(interval)
+				logger.WithField("interval", interval.String()).Info("Message processing interval changed")
+			}
+			if pool := s.currentPool(); pool == nil || pool.Size() != settings.Workers {
+				s.replacePool(newWorkerPool(settings.Workers))
+				logger.WithField("workers", settings.Workers).Info("Worker pool resized")
+			}
 		case t := <-ticker.C:
 			logger.WithField("time", t.Format(time.RFC3339)).Debug("Processing pending messages")
+			s.dispatchPendingMessages(ctx)
+		}
+	}
+}
 
-			messages, err := s.repo.GetUnsentMessages(2)
-			if err != nil {
-				logger.WithError(err).Error("Failed to get unsent messages")
-				continue
+func (s *messageService) dispatchPendingMessages(ctx context.Context) {
+	settings := s.currentSettings()
+
+	// Messages of a paused channel, such as a webhook behind an open circuit
+	// breaker, stay queued until the channel is available again.
+	channels := s.channels.Available()
+	if len(channels) == 0 {
+		logger.WithField("channels", s.channels.Names()).Warn("Every delivery channel is paused, skipping dispatch")
+		return
+	}
+
+	processed := 0
+	for processed < settings.MaxPerTick {
+		limit := settings.BatchSize
+		if remaining := settings.MaxPerTick - processed; remaining < limit {
+			limit = remaining
+		}
+
+		messages, err := s.claimBatch(channels, limit)
+		if err != nil {
+			logger.WithError(err).Error("Failed to claim unsent messages")
+			if len(messages) == 0 {
+				return
 			}
+		}
 
-			logger.WithField("count", len(messages)).Info("Retrieved unsent messages for processing")
-
-			for _, msg := range messages {
-				logger.WithFields(logrus.Fields{
-					"messageID": msg.ID.String(),
-					"to":        msg.To,
-				}).Info("Sending message")
-
-				messageID, err := s.webhookClient.SendMessage(msg)
-				if err != nil {
-					logger.WithFields(logrus.Fields{
-						"messageID": msg.ID.String(),
-						"error":     err.Error(),
-					}).Error("Failed to send message via webhook")
-					continue
+		logger.WithFields(logrus.Fields{
+			"count":    len(messages),
+			"instance": s.instanceID,
+		}).Info("Claimed unsent messages for processing")
+
+		var batch sync.WaitGroup
+		var settled atomic.Int64
+		pool := s.currentPool()
+		for _, msg := range messages {
+			batch.Add(1)
+			pool.Submit(func() {
+				defer batch.Done()
+				if s.sendMessage(ctx, msg) {
+					settled.Add(1)
 				}
+			})
+		}
+		batch.Wait()
+		processed += len(messages)
+
+		// Stop once a batch makes no progress rather than spending the rest of
+		// the tick on claims that keep failing. Deferred messages count as
+		// progress: they are not claimed again this tick, so a paused or rate
+		// limited route does not hold back the messages behind it.
+		if len(messages) < limit || settled.Load() == 0 {
+			break
+		}
+	}
 
-				sentTime := time.Now()
-				logger.WithFields(logrus.Fields{
-					"messageID":    msg.ID.String(),
-					"webhookMsgID": messageID,
-					"sentTime":     sentTime.Format(time.RFC3339),
-				}).Info("Message sent successfully via webhook")
-
-				err = s.repo.UpdateMessageID(msg.ID, messageID)
-				if err != nil {
-					logger.WithFields(logrus.Fields{
-						"messageID":    msg.ID.String(),
-						"webhookMsgID": messageID,
-						"error":        err.Error(),
-					}).Error("Failed to update message ID")
-					continue
-				}
+	logger.WithField("processed", processed).Debug("Finished processing pending messages")
+}
 
-				err = s.repo.UpdateStatus(messageID, entity.StatusSent, sentTime)
-				if err != nil {
-					logger.WithFields(logrus.Fields{
-						"messageID":    msg.ID.String(),
-						"webhookMsgID": messageID,
-						"error":        err.Error(),
-					}).Error("Failed to update message status")
-					continue
-				}
+// claimBatch claims each lane's weighted share of limit and then fills any
+// capacity left by lanes that ran dry from the remaining lanes, most urgent
+// first, so idle lanes never leave workers unused.
+func (s *messageService) claimBatch(channels []string, limit int) ([]entity.Message, error) {
+	allocation := s.lanes.allocate(limit)
 
-				err = s.redisSvc.CacheMessageID(ctx, messageID, sentTime)
-				if err != nil {
-					logger.WithFields(logrus.Fields{
-						"messageID":    msg.ID.String(),
-						"webhookMsgID": messageID,
-						"error":        err.Error(),
-					}).Warn("Failed to cache message ID in Redis")
-				} else {
-					logger.WithFields(logrus.Fields{
-						"messageID":    msg.ID.String(),
-						"webhookMsgID": messageID,
-					}).Debug("Message ID cached in Redis")
-				}
+	var claimed []entity.Message
+	for _, priority := range entity.Priorities {
+		if allocation[priority] == 0 {
+			continue
+		}
+
+		messages, err := s.repo.ClaimPendingMessages(s.instanceID, priority, channels, allocation[priority], s.leaseDuration)
+		if err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, messages...)
+	}
+
+	if spare := limit - len(claimed); spare > 0 {
+		messages, err := s.repo.ClaimPendingMessages(s.instanceID, "", channels, spare, s.leaseDuration)
+		if err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, messages...)
+	}
 
-				logger.WithFields(logrus.Fields{
-					"messageID":    msg.ID.String(),
-					"webhookMsgID": messageID,
-				}).Info("Message processing completed successfully")
+	slices.SortStableFunc(claimed, func(a, b entity.Message) int {
+		return slices.Index(entity.Priorities, a.Priority) - slices.Index(entity.Priorities, b.Priority)
+	})
+
+	return claimed, nil
+}
+
+// sendMessage reports whether the message was sent or deferred to a later
+// time, rather than released or scheduled for a retry.
+func (s *messageService) sendMessage(ctx context.Context, msg entity.Message) bool {
+	logger.WithFields(logrus.Fields{
+		"messageID": msg.ID.String(),
+		"to":        msg.To,
+		"channel":   msg.Channel,
+	}).Info("Sending message")
+
+	sender, err := s.channels.Get(msg.Channel)
+	if err != nil {
+		s.handleSendFailure(msg, err)
+		return false
+	}
+
+	result, err := sender.Send(ctx, msg)
+	var deferredErr *channel.DeferredError
+	if errors.As(err, &deferredErr) {
+		return s.deferUnsent(msg, deferredErr)
+	}
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"messageID": msg.ID.String(),
+			"channel":   msg.Channel,
+			"error":     err.Error(),
+		}).Error("Failed to send message")
+		s.handleSendFailure(msg, err)
+		return false
+	}
+
+	sentTime := time.Now()
+	logger.WithFields(logrus.Fields{
+		"messageID":     msg.ID.String(),
+		"channel":       msg.Channel,
+		"provider":      result.Provider,
+		"providerMsgID": result.MessageID,
+		"sentTime":      sentTime.Format(time.RFC3339),
+	}).Info("Message sent successfully")
+
+	err = s.repo.MarkSent(msg.ID, s.instanceID, result.Provider, result.MessageID, sentTime)
+	if errors.Is(err, repository.ErrMessageNotPending) {
+		logger.WithFields(logrus.Fields{
+			"messageID":     msg.ID.String(),
+			"providerMsgID": result.MessageID,
+		}).Warn("Message was already completed by another instance after its lease expired")
+		return false
+	}
+	if errors.Is(err, repository.ErrClaimLost) {
+		logger.WithFields(logrus.Fields{
+			"messageID":     msg.ID.String(),
+			"providerMsgID": result.MessageID,
+			"instance":      s.instanceID,
+		}).Error("Message was sent after its lease expired and another instance claimed it, it may be sent twice")
+		return false
+	}
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"messageID":     msg.ID.String(),
+			"providerMsgID": result.MessageID,
+			"error":         err.Error(),
+		}).Error("Failed to mark message as sent")
+		return false
+	}
+
+	// A provider may accept a message without returning an ID to cache.
+	if result.MessageID != "" {
+		err = s.redisSvc.CacheMessageID(ctx, result.MessageID, sentTime)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"messageID":     msg.ID.String(),
+				"providerMsgID": result.MessageID,
+				"error":         err.Error(),
+			}).Warn("Failed to cache message ID in Redis")
+		} else {
+			logger.WithFields(logrus.Fields{
+				"messageID":     msg.ID.String(),
+				"providerMsgID": result.MessageID,
+			}).Debug("Message ID cached in Redis")
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"messageID":     msg.ID.String(),
+		"providerMsgID": result.MessageID,
+	}).Info("Message processing completed successfully")
+
+	return true
+}
+
+// deferUnsent requeues a claimed message that a sender held back until it may
+// be sent, without spending one of its attempts.
+func (s *messageService) deferUnsent(msg entity.Message, deferredErr *channel.DeferredError) bool {
+	nextAttemptAt := time.Now().Add(deferredErr.RetryAfter)
+	fields := logrus.Fields{
+		"messageID":     msg.ID.String(),
+		"reason":        deferredErr.Error(),
+		"nextAttemptAt": nextAttemptAt.Format(time.RFC3339),
+	}
+	if err := s.repo.DeferClaim(msg.ID, s.instanceID, deferredErr.Error(), nextAttemptAt); err != nil {
+		if s.claimLost(msg, err) {
+			return false
+		}
+		fields["updateError"] = err.Error()
+		logger.WithFields(fields).Error("Failed to defer message, it will be retried after the lease expires")
+		return false
+	}
+	logger.WithFields(fields).Info("Message deferred")
+	return true
+}
+
+// claimLost reports and logs an update refused because the lease on msg
+// expired and another instance has claimed or completed it since.
+func (s *messageService) claimLost(msg entity.Message, err error) bool {
+	if !errors.Is(err, repository.ErrClaimLost) {
+		return false
+	}
+	logger.WithFields(logrus.Fields{
+		"messageID": msg.ID.String(),
+		"instance":  s.instanceID,
+	}).Warn("Lease on message expired before its outcome was recorded, leaving it to its new owner")
+	return true
+}
+
+func (s *messageService) handleSendFailure(msg entity.Message, sendErr error) {
+	attempts := msg.AttemptCount + 1
+	fields := logrus.Fields{
+		"messageID": msg.ID.String(),
+		"attempts":  attempts,
+		"error":     sendErr.Error(),
+	}
+
+	if !client.IsRetryable(sendErr) || s.retryPolicy.exhausted(attempts) {
+		if err := s.repo.MarkFailed(msg.ID, s.instanceID, sendErr.Error()); err != nil {
+			if s.claimLost(msg, err) {
+				return
 			}
+			fields["updateError"] = err.Error()
+			logger.WithFields(fields).Error("Failed to mark message as failed")
+			return
+		}
+		logger.WithFields(fields).Warn("Message marked as failed")
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(s.retryPolicy.delay(attempts))
+	fields["nextAttemptAt"] = nextAttemptAt.Format(time.RFC3339)
+	if err := s.repo.ScheduleRetry(msg.ID, s.instanceID, sendErr.Error(), nextAttemptAt); err != nil {
+		if s.claimLost(msg, err) {
+			return
 		}
+		fields["updateError"] = err.Error()
+		logger.WithFields(fields).Error("Failed to schedule message retry, it will be retried after the lease expires")
+		return
 	}
+	logger.WithFields(fields).Info("Message retry scheduled")
 }
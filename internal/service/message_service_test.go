@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"auto-message-sender/internal/channel"
+	"auto-message-sender/internal/client"
+	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/model/request"
+	"auto-message-sender/internal/repository"
+)
+
+const testInstanceID = "instance-1"
+
+// fakeRepository records the calls the service makes and answers them
+// with the configured errors. Methods the tests do not use panic through the
+// embedded nil interface.
+type fakeRepository struct {
+	repository.MessageRepository
+
+	mu      sync.Mutex
+	calls   []string
+	errs    map[string]error
+	message *entity.Message
+}
+
+func (r *fakeRepository) record(method, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if owner != "" {
+		method += " " + owner
+	}
+	r.calls = append(r.calls, method)
+	return r.errs[strings.Fields(method)[0]]
+}
+
+func (r *fakeRepository) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+func (r *fakeRepository) GetByID(id uuid.UUID) (*entity.Message, error) {
+	if err := r.record("GetByID", ""); err != nil {
+		return nil, err
+	}
+	return r.message, nil
+}
+
+func (r *fakeRepository) UpdatePending(id uuid.UUID, updates map[string]interface{}) (*entity.Message, error) {
+	if err := r.record("UpdatePending", ""); err != nil {
+		return nil, err
+	}
+	return r.message, nil
+}
+
+func (r *fakeRepository) Cancel(id uuid.UUID) (*entity.Message, error) {
+	if err := r.record("Cancel", ""); err != nil {
+		return nil, err
+	}
+	return r.message, nil
+}
+
+func (r *fakeRepository) RecoverInterruptedSends(owner string) (*repository.RecoveryResult, error) {
+	if err := r.record("RecoverInterruptedSends", owner); err != nil {
+		return nil, err
+	}
+	return &repository.RecoveryResult{}, nil
+}
+
+func (r *fakeRepository) MarkSent(id uuid.UUID, owner, provider, providerID string, sentAt time.Time) error {
+	return r.record("MarkSent", owner)
+}
+
+func (r *fakeRepository) ScheduleRetry(id uuid.UUID, owner, lastError string, nextAttemptAt time.Time) error {
+	return r.record("ScheduleRetry", owner)
+}
+
+func (r *fakeRepository) MarkFailed(id uuid.UUID, owner, lastError string) error {
+	return r.record("MarkFailed", owner)
+}
+
+func (r *fakeRepository) DeferClaim(id uuid.UUID, owner, reason string, nextAttemptAt time.Time) error {
+	return r.record("DeferClaim", owner)
+}
+
+type fakeSender struct {
+	result channel.Result
+	err    error
+}
+
+func (s *fakeSender) Name() string { return entity.ChannelWebhook }
+
+func (s *fakeSender) Send(ctx context.Context, message entity.Message) (channel.Result, error) {
+	return s.result, s.err
+}
+
+type fakeRedisService struct {
+	RedisService
+
+	mu     sync.Mutex
+	cached []string
+}
+
+func (s *fakeRedisService) CacheMessageID(ctx context.Context, messageID string, sentTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = append(s.cached, messageID)
+	return nil
+}
+
+func newTestMessageService(repo *fakeRepository, sender *fakeSender) (*messageService, *fakeRedisService) {
+	redisSvc := &fakeRedisService{}
+	return &messageService{
+		repo:       repo,
+		channels:   channel.NewRegistry(sender),
+		redisSvc:   redisSvc,
+		instanceID: testInstanceID,
+		retryPolicy: retryPolicy{
+			baseDelay:   time.Second,
+			multiplier:  2,
+			maxAttempts: 3,
+		},
+	}, redisSvc
+}
+
+func testMessage() entity.Message {
+	return entity.Message{
+		ID:      uuid.New(),
+		To:      "+905551234567",
+		Content: "Hello",
+		Status:  entity.StatusPending,
+		Channel: entity.ChannelWebhook,
+	}
+}
+
+func TestSendMessageRecordsOutcome(t *testing.T) {
+	sent := channel.Result{Provider: "sms", MessageID: "SM1"}
+	retryable := &client.DeliveryError{Retryable: true, Err: errors.New("timeout")}
+	permanent := &client.DeliveryError{Retryable: false, Err: errors.New("rejected")}
+
+	tests := []struct {
+		name       string
+		sender     *fakeSender
+		attempts   int
+		errs       map[string]error
+		want       bool
+		wantCalls  []string
+		wantCached int
+	}{
+		{
+			name:       "sent",
+			sender:     &fakeSender{result: sent},
+			want:       true,
+			wantCalls:  []string{"MarkSent " + testInstanceID},
+			wantCached: 1,
+		},
+		{
+			name:      "sent after another instance completed it",
+			sender:    &fakeSender{result: sent},
+			errs:      map[string]error{"MarkSent": repository.ErrMessageNotPending},
+			wantCalls: []string{"MarkSent " + testInstanceID},
+		},
+		{
+			name:      "sent after the lease was lost",
+			sender:    &fakeSender{result: sent},
+			errs:      map[string]error{"MarkSent": repository.ErrClaimLost},
+			wantCalls: []string{"MarkSent " + testInstanceID},
+		},
+		{
+			name:      "retryable failure",
+			sender:    &fakeSender{err: retryable},
+			wantCalls: []string{"ScheduleRetry " + testInstanceID},
+		},
+		{
+			name:      "retryable failure after the lease was lost",
+			sender:    &fakeSender{err: retryable},
+			errs:      map[string]error{"ScheduleRetry": repository.ErrClaimLost},
+			wantCalls: []string{"ScheduleRetry " + testInstanceID},
+		},
+		{
+			name:      "last attempt",
+			sender:    &fakeSender{err: retryable},
+			attempts:  2,
+			wantCalls: []string{"MarkFailed " + testInstanceID},
+		},
+		{
+			name:      "permanent failure",
+			sender:    &fakeSender{err: permanent},
+			wantCalls: []string{"MarkFailed " + testInstanceID},
+		},
+		{
+			name:      "deferred",
+			sender:    &fakeSender{err: &channel.DeferredError{RetryAfter: time.Second, Err: channel.ErrPaused}},
+			want:      true,
+			wantCalls: []string{"DeferClaim " + testInstanceID},
+		},
+		{
+			name:      "deferred after the lease was lost",
+			sender:    &fakeSender{err: &channel.DeferredError{RetryAfter: time.Second, Err: channel.ErrPaused}},
+			errs:      map[string]error{"DeferClaim": repository.ErrClaimLost},
+			wantCalls: []string{"DeferClaim " + testInstanceID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{errs: tt.errs}
+			svc, redisSvc := newTestMessageService(repo, tt.sender)
+			msg := testMessage()
+			msg.AttemptCount = tt.attempts
+
+			if got := svc.sendMessage(context.Background(), msg); got != tt.want {
+				t.Errorf("sendMessage() = %v, want %v", got, tt.want)
+			}
+			if got := repo.recorded(); fmt.Sprint(got) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("repository calls = %v, want %v", got, tt.wantCalls)
+			}
+			if len(redisSvc.cached) != tt.wantCached {
+				t.Errorf("cached %d provider IDs, want %d", len(redisSvc.cached), tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestUpdateAndCancelMessage(t *testing.T) {
+	content := func(s string) *request.UpdateMessageRequest { return &request.UpdateMessageRequest{Content: &s} }
+	sendAt := ""
+
+	tests := []struct {
+		name      string
+		cancel    bool
+		req       *request.UpdateMessageRequest
+		errs      map[string]error
+		wantErr   error
+		wantCalls []string
+	}{
+		{
+			name:      "update",
+			req:       content("Updated"),
+			wantCalls: []string{"GetByID", "UpdatePending"},
+		},
+		{
+			name:      "schedule only",
+			req:       &request.UpdateMessageRequest{SendAt: &sendAt},
+			wantCalls: []string{"UpdatePending"},
+		},
+		{
+			name:      "invalid for the channel",
+			req:       content(""),
+			wantErr:   ErrInvalidUpdate,
+			wantCalls: []string{"GetByID"},
+		},
+		{
+			name:      "unknown message",
+			req:       content("Updated"),
+			errs:      map[string]error{"GetByID": gorm.ErrRecordNotFound},
+			wantErr:   ErrMessageNotFound,
+			wantCalls: []string{"GetByID"},
+		},
+		{
+			name:      "claimed or no longer pending",
+			req:       content("Updated"),
+			errs:      map[string]error{"UpdatePending": repository.ErrMessageNotPending},
+			wantErr:   ErrMessageNotPending,
+			wantCalls: []string{"GetByID", "UpdatePending"},
+		},
+		{
+			name:      "cancel",
+			cancel:    true,
+			wantCalls: []string{"Cancel"},
+		},
+		{
+			name:      "cancel claimed or no longer pending",
+			cancel:    true,
+			errs:      map[string]error{"Cancel": repository.ErrMessageNotPending},
+			wantErr:   ErrMessageNotPending,
+			wantCalls: []string{"Cancel"},
+		},
+		{
+			name:      "cancel unknown message",
+			cancel:    true,
+			errs:      map[string]error{"Cancel": gorm.ErrRecordNotFound},
+			wantErr:   ErrMessageNotFound,
+			wantCalls: []string{"Cancel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := testMessage()
+			repo := &fakeRepository{errs: tt.errs, message: &message}
+			svc, _ := newTestMessageService(repo, &fakeSender{})
+
+			var err error
+			if tt.cancel {
+				_, err = svc.CancelMessage(context.Background(), message.ID)
+			} else {
+				_, err = svc.UpdateMessage(context.Background(), message.ID, tt.req)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := repo.recorded(); fmt.Sprint(got) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("repository calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRecoverInterruptedSends(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	for _, wantErr := range []error{nil, failure} {
+		repo := &fakeRepository{errs: map[string]error{"RecoverInterruptedSends": wantErr}}
+		svc, _ := newTestMessageService(repo, &fakeSender{})
+
+		if err := svc.RecoverInterruptedSends(context.Background()); !errors.Is(err, wantErr) {
+			t.Errorf("RecoverInterruptedSends() error = %v, want %v", err, wantErr)
+		}
+		want := []string{"RecoverInterruptedSends " + testInstanceID}
+		if got := repo.recorded(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("repository calls = %v, want %v", got, want)
+		}
+	}
+}
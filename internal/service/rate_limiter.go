@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"auto-message-sender/internal/client"
+	"auto-message-sender/internal/config"
+	"auto-message-sender/pkg/logger"
+)
+
+// RateLimiter limits outbound webhook requests per provider and per recipient
+// with token buckets in Redis, so every replica draws from the same buckets.
+// When Redis cannot be reached requests are allowed rather than held back.
+type RateLimiter interface {
+	client.RateLimiter
+	// Stats returns the limits, the tokens left in the provider buckets and,
+	// when recipient is set, in that recipient's bucket.
+	Stats(ctx context.Context, recipient string) RateLimitStats
+}
+
+type RateLimitStats struct {
+	Enabled   bool
+	Providers map[string]RateLimitBucketStats
+	Recipient RateLimitBucketStats
+}
+
+// RateLimitBucketStats describes one limit. Tokens is nil when the limit is
+// off or the bucket could not be read. The counters are for this instance
+// since it started.
+type RateLimitBucketStats struct {
+	Limit    config.RateLimit
+	Tokens   *float64
+	Allowed  uint64
+	Deferred uint64
+	Errors   uint64
+}
+
+type rateLimiter struct {
+	redisSvc  RedisService
+	enabled   bool
+	providers map[string]*rateLimitBucket
+	recipient *rateLimitBucket
+}
+
+type rateLimitBucket struct {
+	limit    config.RateLimit
+	allowed  atomic.Uint64
+	deferred atomic.Uint64
+	errors   atomic.Uint64
+}
+
+func NewRateLimiter(redisSvc RedisService) RateLimiter {
+	settings := config.AppSettings.RateLimit
+
+	limiter := &rateLimiter{
+		redisSvc:  redisSvc,
+		enabled:   settings.Enabled,
+		providers: make(map[string]*rateLimitBucket),
+		recipient: &rateLimitBucket{limit: settings.Recipient},
+	}
+	for _, provider := range config.AppSettings.Webhook.Providers {
+		limit := provider.RateLimit
+		if limit.Limit == 0 {
+			limit = settings.Provider
+		}
+		limiter.providers[provider.Name] = &rateLimitBucket{limit: limit}
+	}
+
+	if limiter.enabled {
+		logger.WithFields(logrus.Fields{
+			"providerLimit":   settings.Provider.Limit,
+			"providerPeriod":  settings.Provider.Period.String(),
+			"recipientLimit":  settings.Recipient.Limit,
+			"recipientPeriod": settings.Recipient.Period.String(),
+		}).Info("Outbound rate limiting enabled")
+	}
+
+	return limiter
+}
+
+func (l *rateLimiter) AllowProvider(ctx context.Context, provider string) (bool, time.Duration) {
+	bucket, ok := l.providers[provider]
+	if !ok {
+		return true, 0
+	}
+	return l.take(ctx, bucket, fmt.Sprintf("provider:%s", provider))
+}
+
+func (l *rateLimiter) AllowRecipient(ctx context.Context, recipient string) (bool, time.Duration) {
+	return l.take(ctx, l.recipient, fmt.Sprintf("recipient:%s", recipient))
+}
+
+func (l *rateLimiter) take(ctx context.Context, bucket *rateLimitBucket, key string) (bool, time.Duration) {
+	if !l.enabled || bucket.limit.Limit == 0 {
+		return true, 0
+	}
+
+	state, err := l.redisSvc.TakeToken(ctx, key, bucket.limit)
+	if err != nil {
+		bucket.errors.Add(1)
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Warn("Rate limit could not be checked, allowing the request")
+		return true, 0
+	}
+	if !state.Allowed {
+		bucket.deferred.Add(1)
+		return false, state.RetryAfter
+	}
+	bucket.allowed.Add(1)
+	return true, 0
+}
+
+func (l *rateLimiter) Stats(ctx context.Context, recipient string) RateLimitStats {
+	// Recipient buckets are per number, so tokens are only shown for one.
+	recipientKey := ""
+	if recipient != "" {
+		recipientKey = fmt.Sprintf("recipient:%s", recipient)
+	}
+
+	stats := RateLimitStats{
+		Enabled:   l.enabled,
+		Providers: make(map[string]RateLimitBucketStats, len(l.providers)),
+		Recipient: l.bucketStats(ctx, l.recipient, recipientKey),
+	}
+	for name, bucket := range l.providers {
+		stats.Providers[name] = l.bucketStats(ctx, bucket, fmt.Sprintf("provider:%s", name))
+	}
+	return stats
+}
+
+func (l *rateLimiter) bucketStats(ctx context.Context, bucket *rateLimitBucket, key string) RateLimitBucketStats {
+	stats := RateLimitBucketStats{
+		Limit:    bucket.limit,
+		Allowed:  bucket.allowed.Load(),
+		Deferred: bucket.deferred.Load(),
+		Errors:   bucket.errors.Load(),
+	}
+	if !l.enabled || bucket.limit.Limit == 0 || key == "" {
+		return stats
+	}
+
+	state, err := l.redisSvc.PeekTokens(ctx, key, bucket.limit)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Warn("Failed to read rate limit bucket")
+		return stats
+	}
+	stats.Tokens = &state.Tokens
+	return stats
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -16,8 +18,70 @@ import (
 type RedisService interface {
 	CacheMessageID(ctx context.Context, messageID string, sentTime time.Time) error
 	GetMessageSentTime(ctx context.Context, messageID string) (time.Time, error)
+	ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	SaveImportSummary(ctx context.Context, summary ImportSummary, ttl time.Duration) error
+	GetImportSummary(ctx context.Context, importID string) (*ImportSummary, error)
+	AppendImportErrors(ctx context.Context, importID string, rows []string, ttl time.Duration) error
+	GetImportErrors(ctx context.Context, importID string, offset, count int64) ([]string, error)
+	TakeToken(ctx context.Context, key string, limit config.RateLimit) (*TokenBucket, error)
+	PeekTokens(ctx context.Context, key string, limit config.RateLimit) (*TokenBucket, error)
 }
 
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	MessageID   string `json:"message_id,omitempty"`
+}
+
+// TokenBucket is the state of a rate limit bucket after a take or a peek.
+// RetryAfter is how long until a token is available when Allowed is false.
+type TokenBucket struct {
+	Allowed    bool
+	Tokens     float64
+	RetryAfter time.Duration
+}
+
+// tokenBucketScript refills the bucket in KEYS[1] by ARGV[1] tokens per
+// millisecond up to ARGV[2] and takes ARGV[4] tokens from it when enough are
+// left. The Redis clock is used so every replica sees the same refill.
+// It returns whether the tokens were taken, the milliseconds until they can
+// be and the tokens left.
+var tokenBucketScript = redis.NewScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local requested = tonumber(ARGV[4])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= requested then
+  tokens = tokens - requested
+  allowed = 1
+else
+  wait = math.ceil((requested - tokens) / rate)
+end
+
+if requested > 0 then
+  redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+  redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return {allowed, wait, tostring(tokens)}
+`)
+
 type redisService struct {
 	client *redis.Client
 }
@@ -98,3 +162,227 @@ func (s *redisService) GetMessageSentTime(ctx context.Context, messageID string)
 
 	return sentTime, nil
 }
+
+// ReserveIdempotencyKey stores an in-progress record for key unless one
+// already exists. It returns nil when the caller won the reservation and the
+// existing record otherwise.
+func (s *redisService) ReserveIdempotencyKey(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	redisKey := fmt.Sprintf("idempotency:%s", key)
+
+	payload, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	reserved, err := s.client.SetNX(ctx, redisKey, payload, ttl).Result()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"error": err.Error(),
+		}).Error("Failed to reserve idempotency key in Redis")
+		return nil, err
+	}
+	if reserved {
+		logger.WithField("key", redisKey).Debug("Reserved idempotency key in Redis")
+		return nil, nil
+	}
+
+	val, err := s.client.Get(ctx, redisKey).Result()
+	if err != nil {
+		if err == redis.Nil {
+			// The previous record expired between SETNX and GET; try again.
+			return s.ReserveIdempotencyKey(ctx, key, fingerprint, ttl)
+		}
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"error": err.Error(),
+		}).Error("Failed to get idempotency record from Redis")
+		return nil, err
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"value": val,
+			"error": err.Error(),
+		}).Error("Failed to parse idempotency record from Redis")
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"key":       redisKey,
+		"messageID": record.MessageID,
+	}).Debug("Found existing idempotency record in Redis")
+
+	return &record, nil
+}
+
+func (s *redisService) CompleteIdempotencyKey(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	redisKey := fmt.Sprintf("idempotency:%s", key)
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.Set(ctx, redisKey, payload, ttl).Err(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"error": err.Error(),
+		}).Error("Failed to store idempotency record in Redis")
+		return err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"key":       redisKey,
+		"messageID": record.MessageID,
+	}).Debug("Stored idempotency record in Redis")
+	return nil
+}
+
+func (s *redisService) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	redisKey := fmt.Sprintf("idempotency:%s", key)
+
+	if err := s.client.Del(ctx, redisKey).Err(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"error": err.Error(),
+		}).Error("Failed to release idempotency key in Redis")
+		return err
+	}
+
+	return nil
+}
+
+func (s *redisService) SaveImportSummary(ctx context.Context, summary ImportSummary, ttl time.Duration) error {
+	key := fmt.Sprintf("import:%s", summary.ImportID)
+
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.Set(ctx, key, payload, ttl).Err(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to store import summary in Redis")
+		return err
+	}
+
+	return nil
+}
+
+func (s *redisService) GetImportSummary(ctx context.Context, importID string) (*ImportSummary, error) {
+	key := fmt.Sprintf("import:%s", importID)
+
+	val, err := s.client.Get(ctx, key).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logger.WithFields(logrus.Fields{
+				"key":   key,
+				"error": err.Error(),
+			}).Error("Failed to get import summary from Redis")
+		}
+		return nil, err
+	}
+
+	var summary ImportSummary
+	if err := json.Unmarshal([]byte(val), &summary); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to parse import summary from Redis")
+		return nil, err
+	}
+
+	return &summary, nil
+}
+
+func (s *redisService) AppendImportErrors(ctx context.Context, importID string, rows []string, ttl time.Duration) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("import:%s:errors", importID)
+
+	values := make([]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"count": len(rows),
+			"error": err.Error(),
+		}).Error("Failed to append import errors in Redis")
+		return err
+	}
+
+	return nil
+}
+
+func (s *redisService) GetImportErrors(ctx context.Context, importID string, offset, count int64) ([]string, error) {
+	key := fmt.Sprintf("import:%s:errors", importID)
+
+	rows, err := s.client.LRange(ctx, key, offset, offset+count-1).Result()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   key,
+			"error": err.Error(),
+		}).Error("Failed to get import errors from Redis")
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// TakeToken takes one token from the bucket at key, which holds up to
+// limit.Burst tokens and refills at limit.Limit tokens per limit.Period.
+func (s *redisService) TakeToken(ctx context.Context, key string, limit config.RateLimit) (*TokenBucket, error) {
+	return s.runTokenBucket(ctx, key, limit, 1)
+}
+
+// PeekTokens returns the bucket at key without taking a token.
+func (s *redisService) PeekTokens(ctx context.Context, key string, limit config.RateLimit) (*TokenBucket, error) {
+	return s.runTokenBucket(ctx, key, limit, 0)
+}
+
+func (s *redisService) runTokenBucket(ctx context.Context, key string, limit config.RateLimit, requested int) (*TokenBucket, error) {
+	redisKey := fmt.Sprintf("ratelimit:%s", key)
+
+	rate := float64(limit.Limit) / float64(limit.Period.Milliseconds())
+	// An idle bucket is full again after burst/rate, so it can expire then.
+	ttl := int64(float64(limit.Burst)/rate) + time.Second.Milliseconds()
+
+	result, err := tokenBucketScript.Run(ctx, s.client, []string{redisKey}, rate, limit.Burst, ttl, requested).Slice()
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"key":   redisKey,
+			"error": err.Error(),
+		}).Error("Failed to run rate limit script in Redis")
+		return nil, err
+	}
+	if len(result) != 3 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	wait, _ := result[1].(int64)
+	tokensValue, _ := result[2].(string)
+	tokens, err := strconv.ParseFloat(tokensValue, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected rate limit tokens %q: %w", tokensValue, err)
+	}
+
+	return &TokenBucket{
+		Allowed:    allowed == 1,
+		Tokens:     tokens,
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"auto-message-sender/internal/config"
+)
+
+type retryPolicy struct {
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	multiplier  float64
+	jitter      float64
+	maxAttempts int
+}
+
+func newRetryPolicy() retryPolicy {
+	return retryPolicy{
+		baseDelay:   config.AppSettings.Retry.BaseDelay,
+		maxDelay:    config.AppSettings.Retry.MaxDelay,
+		multiplier:  config.AppSettings.Retry.Multiplier,
+		jitter:      config.AppSettings.Retry.Jitter,
+		maxAttempts: config.AppSettings.Retry.MaxAttempts,
+	}
+}
+
+func (p retryPolicy) exhausted(attempts int) bool {
+	return attempts >= p.maxAttempts
+}
+
+// delay returns how long to wait after the given number of failed attempts:
+// baseDelay * multiplier^(attempts-1), capped at maxDelay and spread by
+// +/- jitter so that messages failing together do not retry together.
+func (p retryPolicy) delay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	d := float64(p.baseDelay) * math.Pow(p.multiplier, float64(attempts-1))
+	if p.maxDelay > 0 && d > float64(p.maxDelay) {
+		d = float64(p.maxDelay)
+	}
+
+	if p.jitter > 0 {
+		d *= 1 + p.jitter*(2*rand.Float64()-1)
+	}
+
+	return time.Duration(d)
+}
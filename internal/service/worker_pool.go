@@ -0,0 +1,91 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type WorkerPoolStats struct {
+	Workers     int
+	Busy        int
+	Queued      int
+	Processed   uint64
+	Utilisation float64
+}
+
+type workerPool struct {
+	size      int
+	jobs      chan func()
+	wg        sync.WaitGroup
+	busy      atomic.Int64
+	processed atomic.Uint64
+	busyNanos atomic.Int64
+	startedAt time.Time
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &workerPool{
+		size:      size,
+		jobs:      make(chan func(), size),
+		startedAt: time.Now(),
+	}
+
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *workerPool) work() {
+	defer p.wg.Done()
+
+	for job := range p.jobs {
+		p.busy.Add(1)
+		start := time.Now()
+		job()
+		p.busyNanos.Add(int64(time.Since(start)))
+		p.busy.Add(-1)
+		p.processed.Add(1)
+	}
+}
+
+// Submit blocks while every worker is busy and the queue is full, which keeps
+// the number of in-flight webhook calls bounded by the pool size.
+func (p *workerPool) Submit(job func()) {
+	p.jobs <- job
+}
+
+// Stop lets the workers finish every queued job and waits for them to exit.
+func (p *workerPool) Stop() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+func (p *workerPool) Size() int {
+	return p.size
+}
+
+// Stats reports the current load and the share of worker time spent on jobs
+// since the pool was started.
+func (p *workerPool) Stats() WorkerPoolStats {
+	stats := WorkerPoolStats{
+		Workers:   p.size,
+		Busy:      int(p.busy.Load()),
+		Queued:    len(p.jobs),
+		Processed: p.processed.Load(),
+	}
+
+	capacity := time.Since(p.startedAt) * time.Duration(p.size)
+	if capacity > 0 {
+		stats.Utilisation = float64(p.busyNanos.Load()) / float64(capacity)
+	}
+
+	return stats
+}
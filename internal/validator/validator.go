@@ -2,14 +2,23 @@ package validator
 
 import (
 	"fmt"
+	"net/mail"
 	"strings"
+	"text/template"
 	"time"
 
 	"auto-message-sender/internal/entity"
+	"auto-message-sender/internal/exporter"
+	"auto-message-sender/internal/importer"
 
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	maxEmailSubjectLength = 255
+	maxEmailBodyLength    = 256 * 1024
+)
+
 type CustomValidator struct {
 	validator *validator.Validate
 }
@@ -51,19 +60,250 @@ func ValidatePhoneNumber(phone string) error {
 	return nil
 }
 
+func ValidateEmailAddress(address string) error {
+	if len(address) == 0 {
+		return fmt.Errorf("email address is required")
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address || parsed.Name != "" {
+		return fmt.Errorf("email address must be a bare address such as user@example.com")
+	}
+
+	if !strings.Contains(address[strings.LastIndex(address, "@")+1:], ".") {
+		return fmt.Errorf("email address must have a fully qualified domain")
+	}
+
+	return nil
+}
+
+// ValidateMessageBody validates the content fields in the form the channel
+// sends them. Subject and HTML are only accepted by the email channel.
+func ValidateMessageBody(channel, subject, content, html string) error {
+	if channel != entity.ChannelEmail {
+		if subject != "" || html != "" {
+			return fmt.Errorf("subject and html are only supported by the email channel")
+		}
+		return ValidateMessageContent(content)
+	}
+
+	if err := ValidateEmailSubject(subject); err != nil {
+		return err
+	}
+
+	if content == "" && html == "" {
+		return fmt.Errorf("either content or html is required for email")
+	}
+
+	return ValidateEmailBody(content, html)
+}
+
+func ValidateEmailSubject(subject string) error {
+	if len(subject) == 0 {
+		return fmt.Errorf("subject is required for email")
+	}
+
+	if len(subject) > maxEmailSubjectLength {
+		return fmt.Errorf("subject too long, maximum length is %d characters", maxEmailSubjectLength)
+	}
+
+	if strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("subject must be a single line")
+	}
+
+	return nil
+}
+
+func ValidateEmailBody(content, html string) error {
+	if len(content)+len(html) > maxEmailBodyLength {
+		return fmt.Errorf("email body too long, maximum size is %d bytes", maxEmailBodyLength)
+	}
+
+	return nil
+}
+
+func ValidateSendAt(sendAt string, maxAhead time.Duration) error {
+	if sendAt == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, sendAt)
+	if err != nil {
+		return fmt.Errorf("send_at must be an RFC3339 timestamp with a timezone, e.g. 2006-01-02T15:04:05+03:00")
+	}
+
+	now := time.Now()
+	if t.Before(now) {
+		return fmt.Errorf("send_at must not be in the past")
+	}
+
+	if maxAhead > 0 && t.After(now.Add(maxAhead)) {
+		return fmt.Errorf("send_at must be within %s from now", maxAhead)
+	}
+
+	return nil
+}
+
+func ValidatePriority(priority string) error {
+	if priority == "" {
+		return nil
+	}
+
+	for _, validPriority := range entity.Priorities {
+		if priority == validPriority {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("priority must be one of: %s", strings.Join(entity.Priorities, ", "))
+}
+
+func ValidateChannel(channel string, enabled []string) error {
+	if channel == "" {
+		return nil
+	}
+
+	for _, enabledChannel := range enabled {
+		if channel == enabledChannel {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("channel must be one of the enabled channels: %s", strings.Join(enabled, ", "))
+}
+
+// ValidateRecipient validates the recipient in the format the channel
+// expects it in.
+func ValidateRecipient(channel, to string) error {
+	switch channel {
+	case entity.ChannelEmail:
+		return ValidateEmailAddress(to)
+	default:
+		return ValidatePhoneNumber(to)
+	}
+}
+
+func ValidateBatchLength(length, maxItems int) error {
+	if length == 0 {
+		return fmt.Errorf("batch must contain at least one message")
+	}
+
+	if maxItems > 0 && length > maxItems {
+		return fmt.Errorf("batch must contain at most %d messages", maxItems)
+	}
+
+	return nil
+}
+
+func ValidateImportFormat(format string) error {
+	for _, validFormat := range importer.Formats {
+		if format == validFormat {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("format must be one of: %s", strings.Join(importer.Formats, ", "))
+}
+
+func ValidateExportFormat(format string) error {
+	for _, validFormat := range exporter.Formats {
+		if format == validFormat {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("format must be one of: %s", strings.Join(exporter.Formats, ", "))
+}
+
+func ValidateContentTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+
+	if _, err := template.New("content").Parse(text); err != nil {
+		return fmt.Errorf("template is not a valid Go text/template: %s", err.Error())
+	}
+
+	return nil
+}
+
+func ValidateIdempotencyKey(key string) error {
+	if len(key) > 255 {
+		return fmt.Errorf("idempotency key must be at most 255 characters")
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return fmt.Errorf("idempotency key must contain only printable ASCII characters")
+		}
+	}
+
+	return nil
+}
+
 func ValidateStatus(status string) error {
 	if status == "" {
 		return nil
 	}
 
-	validStatuses := []string{entity.StatusPending, entity.StatusSent, entity.StatusFailed}
-	for _, validStatus := range validStatuses {
+	for _, validStatus := range entity.Statuses {
 		if status == validStatus {
 			return nil
 		}
 	}
 
-	return fmt.Errorf("status must be one of: %s", strings.Join(validStatuses, ", "))
+	return fmt.Errorf("status must be one of: %s", strings.Join(entity.Statuses, ", "))
+}
+
+func ValidateDeliveryStatus(status string) error {
+	if status != entity.StatusDelivered && status != entity.StatusUndelivered {
+		return fmt.Errorf("status must be one of: %s, %s", entity.StatusDelivered, entity.StatusUndelivered)
+	}
+
+	return nil
+}
+
+// ValidateStatuses validates repeated or comma separated status filters.
+// all is accepted in addition to the message statuses.
+func ValidateStatuses(values []string, all string) error {
+	for _, value := range values {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status == all {
+				continue
+			}
+			if err := ValidateStatus(status); err != nil {
+				return fmt.Errorf("status must be %s or a list of: %s", all, strings.Join(entity.Statuses, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func ValidatePhonePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "+") {
+		return fmt.Errorf("recipient prefix must begin with '+'")
+	}
+
+	for i := 1; i < len(prefix); i++ {
+		if prefix[i] < '0' || prefix[i] > '9' {
+			return fmt.Errorf("recipient prefix must contain only digits after '+' prefix")
+		}
+	}
+
+	return nil
+}
+
+func ValidateContentSearch(content string) error {
+	if len(content) > 160 {
+		return fmt.Errorf("content search cannot exceed 160 characters")
+	}
+
+	return nil
 }
 
 func ValidateDate(date string) error {
@@ -101,6 +341,55 @@ func ValidateDateRange(startDate, endDate string) error {
 	return nil
 }
 
+// ValidateTimeRange validates a range whose bounds are either YYYY-MM-DD
+// dates or RFC3339 timestamps.
+func ValidateTimeRange(from, to string) error {
+	start, err := parseDateOrTime(from)
+	if err != nil {
+		return fmt.Errorf("created_from %v", err)
+	}
+	end, err := parseDateOrTime(to)
+	if err != nil {
+		return fmt.Errorf("created_to %v", err)
+	}
+
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return fmt.Errorf("created_to must be after created_from")
+	}
+
+	return nil
+}
+
+func parseDateOrTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a YYYY-MM-DD date or an RFC3339 timestamp")
+	}
+	return t, nil
+}
+
+// ValidateSort validates a sort parameter of the form field or -field.
+func ValidateSort(sort string, fields []string) error {
+	if sort == "" {
+		return nil
+	}
+
+	field := strings.TrimPrefix(sort, "-")
+	for _, allowed := range fields {
+		if field == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("sort must be one of: %s (prefix with '-' for descending)", strings.Join(fields, ", "))
+}
+
 func ValidatePageParams(page, pageSize int) error {
 	if page < 1 {
 		return fmt.Errorf("page number must be at least 1")
@@ -112,3 +401,58 @@ func ValidatePageParams(page, pageSize int) error {
 
 	return nil
 }
+
+func ValidateDispatcherInterval(interval string) error {
+	if interval == "" {
+		return fmt.Errorf("interval is required")
+	}
+
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return fmt.Errorf("interval must be a duration such as 30s or 2m")
+	}
+
+	if d < time.Second || d > 24*time.Hour {
+		return fmt.Errorf("interval must be between 1s and 24h")
+	}
+
+	return nil
+}
+
+func ValidateDispatcherBatchSize(batchSize int) error {
+	if batchSize < 1 || batchSize > 1000 {
+		return fmt.Errorf("batch size must be between 1 and 1000")
+	}
+
+	return nil
+}
+
+func ValidateDispatcherWorkers(workers int) error {
+	if workers < 1 || workers > 256 {
+		return fmt.Errorf("workers must be between 1 and 256")
+	}
+
+	return nil
+}
+
+func ValidateDispatcherMaxPerTick(maxPerTick int) error {
+	if maxPerTick < 1 || maxPerTick > 100000 {
+		return fmt.Errorf("max per tick must be between 1 and 100000")
+	}
+
+	return nil
+}
+
+func ValidatePagination(pagination, cursor string) error {
+	switch pagination {
+	case "", "cursor":
+		return nil
+	case "offset":
+		if cursor != "" {
+			return fmt.Errorf("cursor cannot be used with offset pagination")
+		}
+		return nil
+	default:
+		return fmt.Errorf("pagination must be one of: offset, cursor")
+	}
+}
@@ -0,0 +1,96 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"auto-message-sender/internal/entity"
+)
+
+func TestValidateRecipient(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		to      string
+		wantErr bool
+	}{
+		{"webhook phone number", entity.ChannelWebhook, "+905551234567", false},
+		{"webhook missing plus", entity.ChannelWebhook, "905551234567", true},
+		{"webhook letters", entity.ChannelWebhook, "+90555abc", true},
+		{"webhook empty", entity.ChannelWebhook, "", true},
+		{"webhook email address", entity.ChannelWebhook, "user@example.com", true},
+		{"file phone number", entity.ChannelFile, "+905551234567", false},
+		{"file missing plus", entity.ChannelFile, "905551234567", true},
+		{"email address", entity.ChannelEmail, "user@example.com", false},
+		{"email display name", entity.ChannelEmail, "User <user@example.com>", true},
+		{"email without domain dot", entity.ChannelEmail, "user@localhost", true},
+		{"email phone number", entity.ChannelEmail, "+905551234567", true},
+		{"email empty", entity.ChannelEmail, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRecipient(tt.channel, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateRecipient(%q, %q) error = %v, wantErr %v", tt.channel, tt.to, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMessageBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		subject string
+		content string
+		html    string
+		wantErr bool
+	}{
+		{"webhook content", entity.ChannelWebhook, "", "Hello", "", false},
+		{"webhook empty content", entity.ChannelWebhook, "", "", "", true},
+		{"webhook content too long", entity.ChannelWebhook, "", strings.Repeat("a", 161), "", true},
+		{"webhook subject", entity.ChannelWebhook, "Hi", "Hello", "", true},
+		{"webhook html", entity.ChannelWebhook, "", "Hello", "<p>Hello</p>", true},
+		{"email content", entity.ChannelEmail, "Hi", "Hello", "", false},
+		{"email html only", entity.ChannelEmail, "Hi", "", "<p>Hello</p>", false},
+		{"email long content", entity.ChannelEmail, "Hi", strings.Repeat("a", 1000), "", false},
+		{"email missing subject", entity.ChannelEmail, "", "Hello", "", true},
+		{"email multiline subject", entity.ChannelEmail, "Hi\r\nBcc: x@example.com", "Hello", "", true},
+		{"email subject too long", entity.ChannelEmail, strings.Repeat("a", maxEmailSubjectLength+1), "Hello", "", true},
+		{"email empty body", entity.ChannelEmail, "Hi", "", "", true},
+		{"email body too large", entity.ChannelEmail, "Hi", strings.Repeat("a", maxEmailBodyLength), "<p></p>", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessageBody(tt.channel, tt.subject, tt.content, tt.html)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateMessageBody() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateChannel(t *testing.T) {
+	enabled := []string{entity.ChannelWebhook}
+	tests := []struct {
+		name    string
+		channel string
+		wantErr bool
+	}{
+		{"default channel", "", false},
+		{"enabled channel", entity.ChannelWebhook, false},
+		{"unknown channel", "sms", true},
+		{"disabled channel", entity.ChannelFile, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChannel(tt.channel, enabled)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateChannel(%q) error = %v, wantErr %v", tt.channel, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,18 +13,27 @@ const (
 	StatusUp       Status = "UP"
 	StatusDown     Status = "DOWN"
 	StatusDisabled Status = "DISABLED"
+	StatusDegraded Status = "DEGRADED"
 )
 
 type Response struct {
-	Status    Status            `json:"status"`
-	Timestamp string            `json:"timestamp"`
-	Version   string            `json:"version"`
-	Services  map[string]string `json:"services"`
+	Status    Status                 `json:"status"`
+	Timestamp string                 `json:"timestamp"`
+	Version   string                 `json:"version"`
+	Services  map[string]string      `json:"services"`
+	Details   map[string]interface{} `json:"details,omitempty"`
 }
 
+// Indicator reports the state of a dependency and details about it.
+type Indicator func() (Status, interface{})
+
 type Config struct {
 	Version string
 	DB      *gorm.DB
+	// Indicators are shown under services and details but do not change the
+	// overall status, so an outage of an upstream provider does not make the
+	// service itself look unhealthy.
+	Indicators map[string]Indicator
 }
 
 func RegisterRoutes(e *echo.Echo, config Config) {
@@ -63,6 +72,17 @@ func Handler(config Config) echo.HandlerFunc {
 			health.Services["database"] = string(StatusDisabled)
 		}
 
+		for name, indicator := range config.Indicators {
+			status, details := indicator()
+			health.Services[name] = string(status)
+			if details != nil {
+				if health.Details == nil {
+					health.Details = map[string]interface{}{}
+				}
+				health.Details[name] = details
+			}
+		}
+
 		statusCode := http.StatusOK
 		if health.Status == StatusDown {
 			statusCode = http.StatusServiceUnavailable
@@ -0,0 +1,287 @@
+// Package smtptest provides an in-process SMTP server for exercising the
+// email channel without a real mail server, in the spirit of httptest.
+//
+//	server := smtptest.NewServer()
+//	defer server.Close()
+//	// point email.host and email.port at server.Host() and server.Port()
+//	messages := server.Messages()
+//
+// The server speaks enough of RFC 5321 for net/smtp: EHLO/HELO, AUTH PLAIN,
+// MAIL, RCPT, DATA, RSET, NOOP and QUIT. It does not offer STARTTLS, so
+// clients must be configured with email.starttls set to disabled or
+// opportunistic.
+package smtptest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is an email accepted by the server.
+type Message struct {
+	From     string
+	To       []string
+	Username string
+	Data     string
+}
+
+// Reply is an SMTP reply the server sends instead of its normal answer.
+type Reply struct {
+	Code    int
+	Message string
+}
+
+type Server struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []Message
+	// rejections maps a command (MAIL, RCPT, DATA or AUTH) to the replies
+	// returned for its next uses, consumed in order.
+	rejections map[string][]Reply
+	users      map[string]string
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a server on a random loopback port.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("smtptest: failed to listen: %v", err))
+	}
+
+	server := &Server{
+		listener:   listener,
+		rejections: make(map[string][]Reply),
+		users:      make(map[string]string),
+	}
+	server.wg.Add(1)
+	go server.serve()
+	return server
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	number, _ := strconv.Atoi(port)
+	return number
+}
+
+// RequireAuth makes the server advertise AUTH PLAIN and accept only the
+// given credentials.
+func (s *Server) RequireAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// Reject makes the next use of command answer with reply, for example
+// Reject("RCPT", Reply{550, "no such user"}) for a permanent failure or
+// Reject("DATA", Reply{451, "try again later"}) for a transient one.
+func (s *Server) Reject(command string, reply Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	command = strings.ToUpper(command)
+	s.rejections[command] = append(s.rejections[command], reply)
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+type session struct {
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	message  Message
+	username string
+}
+
+func (s *session) reply(code int, lines ...string) {
+	for i, line := range lines {
+		separator := " "
+		if i < len(lines)-1 {
+			separator = "-"
+		}
+		fmt.Fprintf(s.writer, "%d%s%s\r\n", code, separator, line)
+	}
+	s.writer.Flush()
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sess := &session{reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	sess.reply(220, "smtptest ready")
+
+	for {
+		line, err := sess.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		verb = strings.ToUpper(verb)
+
+		if reply, ok := s.rejection(verb); ok {
+			sess.reply(reply.Code, reply.Message)
+			continue
+		}
+
+		switch verb {
+		case "EHLO":
+			lines := []string{"smtptest"}
+			if s.authRequired() {
+				lines = append(lines, "AUTH PLAIN")
+			}
+			sess.reply(250, lines...)
+		case "HELO":
+			sess.reply(250, "smtptest")
+		case "AUTH":
+			s.auth(sess, arg)
+		case "MAIL":
+			if s.authRequired() && sess.username == "" {
+				sess.reply(530, "authentication required")
+				continue
+			}
+			sess.message = Message{From: addressArg(arg), Username: sess.username}
+			sess.reply(250, "OK")
+		case "RCPT":
+			if sess.message.From == "" {
+				sess.reply(503, "need MAIL first")
+				continue
+			}
+			sess.message.To = append(sess.message.To, addressArg(arg))
+			sess.reply(250, "OK")
+		case "DATA":
+			if len(sess.message.To) == 0 {
+				sess.reply(503, "need RCPT first")
+				continue
+			}
+			sess.reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := readData(sess.reader)
+			if err != nil {
+				return
+			}
+			sess.message.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, sess.message)
+			s.mu.Unlock()
+			sess.message = Message{}
+			sess.reply(250, "OK: queued")
+		case "RSET":
+			sess.message = Message{}
+			sess.reply(250, "OK")
+		case "NOOP":
+			sess.reply(250, "OK")
+		case "QUIT":
+			sess.reply(221, "bye")
+			return
+		default:
+			sess.reply(502, "command not implemented")
+		}
+	}
+}
+
+func (s *Server) auth(sess *session, arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		sess.reply(504, "unrecognized authentication type")
+		return
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		sess.reply(501, "invalid credentials encoding")
+		return
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	if len(parts) != 3 {
+		sess.reply(501, "invalid credentials")
+		return
+	}
+
+	s.mu.Lock()
+	password, ok := s.users[parts[1]]
+	s.mu.Unlock()
+	if !ok || password != parts[2] {
+		sess.reply(535, "authentication failed")
+		return
+	}
+
+	sess.username = parts[1]
+	sess.reply(235, "authentication succeeded")
+}
+
+func (s *Server) authRequired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) > 0
+}
+
+func (s *Server) rejection(verb string) (Reply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	replies := s.rejections[verb]
+	if len(replies) == 0 {
+		return Reply{}, false
+	}
+	s.rejections[verb] = replies[1:]
+	return replies[0], true
+}
+
+// addressArg extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func addressArg(arg string) string {
+	_, address, _ := strings.Cut(arg, ":")
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
+
+func readData(reader *bufio.Reader) (string, error) {
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data.String(), nil
+		}
+		data.WriteString(strings.TrimPrefix(line, "."))
+	}
+}